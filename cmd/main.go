@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 
@@ -140,8 +143,9 @@ func main() {
 					Action: func(c *cli.Context) error {
 						client, err := zbc.NewClient(conf.Broker.String())
 						isFatal(err)
+						defer client.Close()
 						log.Println("Connected to Zeebe.")
-						topic, err := client.CreateTopic(c.String("name"), c.Int("partitions"))
+						topic, err := client.CreateTopic(context.Background(), c.String("name"), c.Int("partitions"))
 						isFatal(err)
 
 						fmt.Println(topic.State)
@@ -166,9 +170,10 @@ func main() {
 						isFatal(err)
 						client, err := zbc.NewClient(conf.Broker.String())
 						isFatal(err)
+						defer client.Close()
 						log.Println("Connected to Zeebe.")
 
-						createdTask, err := client.CreateTask(c.String("topic"), &task)
+						createdTask, err := client.CreateTask(context.Background(), c.String("topic"), &task)
 						isFatal(err)
 
 						fmt.Println(createdTask.State)
@@ -200,8 +205,9 @@ func main() {
 
 						client, err := zbc.NewClient(conf.Broker.String())
 						isFatal(err)
+						defer client.Close()
 
-						workflow, err := client.CreateWorkflowFromFile(c.String("topic"), resourceType, filename)
+						workflow, err := client.CreateWorkflowFromFile(context.Background(), c.String("topic"), resourceType, filename)
 						isFatal(err)
 
 						fmt.Println(workflow.State)
@@ -227,16 +233,18 @@ func main() {
 
 						client, err := zbc.NewClient(conf.Broker.String())
 						isFatal(err)
+						defer client.Close()
 						log.Println("Connected to Zeebe.")
 
-						createdInstance, err := client.CreateWorkflowInstance(c.String("topic"), &workflowInstance)
-						isFatal(err)
-
-						fmt.Println(createdInstance.State)
-						if createdInstance.State == zbc.WorkflowInstanceRejected {
+						createdInstance, err := client.CreateWorkflowInstance(context.Background(), c.String("topic"), &workflowInstance)
+						if zbc.IsRejection(err) {
+							fmt.Println(createdInstance.State)
 							fmt.Println("\nDid you deploy the workflow?")
+							return nil
 						}
+						isFatal(err)
 
+						fmt.Println(createdInstance.State)
 						return nil
 					},
 				},
@@ -274,8 +282,9 @@ func main() {
 					Action: func(c *cli.Context) error {
 						client, err := zbc.NewClient(conf.Broker.String())
 						isFatal(err)
+						defer client.Close()
 
-						subscriptionCh, subscription, err := client.TaskConsumer(c.String("topic"), c.String("lock-owner"), c.String("task-type"))
+						subscriptionCh, subscription, err := client.TaskConsumer(context.Background(), c.String("topic"), c.String("lock-owner"), c.String("task-type"))
 						isFatal(err)
 
 						osCh := make(chan os.Signal, 1)
@@ -283,20 +292,19 @@ func main() {
 						go func() {
 							<-osCh
 							fmt.Println("Closing subscription.")
-							errs := client.CloseTaskSubscription(subscription)
+							errs := client.CloseTaskSubscription(context.Background(), subscription)
 							if len(errs) > 0 {
 								fmt.Println("failed to close subscription: ", errs)
 							} else {
 								fmt.Println("Subscription closed.")
 							}
-							os.Exit(0)
 						}()
 
 						log.Println("Waiting for events ....")
-						for {
-							message := <-subscriptionCh
+						for message := range subscriptionCh {
 							fmt.Println(message.String())
 						}
+						return nil
 					},
 				},
 				{
@@ -326,8 +334,9 @@ func main() {
 					Action: func(c *cli.Context) error {
 						client, err := zbc.NewClient(conf.Broker.String())
 						isFatal(err)
+						defer client.Close()
 						log.Println("Connected to Zeebe.")
-						subscriptionCh, sub, err := client.TopicConsumer(c.String("topic"), c.String("subscription-name"), c.Int64("start-position"))
+						subscriptionCh, sub, err := client.TopicConsumer(context.Background(), c.String("topic"), c.String("subscription-name"), c.Int64("start-position"))
 						isFatal(err)
 
 						osCh := make(chan os.Signal, 1)
@@ -335,20 +344,94 @@ func main() {
 						go func() {
 							<-osCh
 							fmt.Println("Closing subscription.")
-							errs := client.CloseTopicSubscription(sub)
+							errs := client.CloseTopicSubscription(context.Background(), sub)
 							if len(errs) > 0 {
 								fmt.Println("failed to close subscription: ", err)
 							} else {
 								fmt.Println("Subscription closed.")
 							}
-							os.Exit(0)
 						}()
 
-						for {
-							message := <-subscriptionCh
+						for message := range subscriptionCh {
 							fmt.Println(message.String())
 						}
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:    "deadletter",
+			Aliases: []string{"dl"},
+			Usage:   "inspect and replay tasks which exhausted their retries",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:   "file, f",
+					Value:  "dead-letters.ndjson",
+					Usage:  "Location of the dead-letter file.",
+					EnvVar: "ZB_DEAD_LETTER_FILE",
+				},
+			},
+			Subcommands: []cli.Command{
+				{
+					Name:    "list",
+					Aliases: []string{"ls"},
+					Usage:   "list dead-lettered tasks",
+					Action: func(c *cli.Context) error {
+						sink := zbc.NewFileDeadLetterSink(c.Parent().String("file"))
+						letters, err := sink.List()
+						isFatal(err)
+
+						w := tabwriter.NewWriter(os.Stdout, 0, 0, 4, ' ', 0)
+						fmt.Fprintln(w, "PartitionID	Key	Type	Time	Error")
+						for _, letter := range letters {
+							line := fmt.Sprintf("%d\t%d\t%s\t%s\t%s", letter.PartitionID, letter.Key, letter.Task.Type, letter.Time.Format(time.RFC3339), letter.Error)
+							fmt.Fprintln(w, line)
+						}
+						w.Flush()
+						return nil
+					},
+				},
+				{
+					Name:      "replay",
+					Usage:     "reset the retries of a dead-lettered task so it is handed out again",
+					ArgsUsage: "<partition> <key>",
+					Flags: []cli.Flag{
+						cli.IntFlag{
+							Name:  "retries, r",
+							Value: 3,
+							Usage: "Specify the retries of the replayed task.",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						// Keys are unique within a partition only.
+						partitionID, err := strconv.ParseUint(c.Args().Get(0), 10, 16)
+						isFatal(err)
+						key, err := strconv.ParseUint(c.Args().Get(1), 10, 64)
+						isFatal(err)
+
+						sink := zbc.NewFileDeadLetterSink(c.Parent().String("file"))
+						letters, err := sink.List()
+						isFatal(err)
+
+						var letter *zbc.DeadLetter
+						for _, l := range letters {
+							if l.PartitionID == uint16(partitionID) && l.Key == key {
+								letter = l
+							}
+						}
+						if letter == nil {
+							isFatal(fmt.Errorf("no dead-lettered task with key %d on partition %d", key, partitionID))
+						}
 
+						client, err := zbc.NewClient(conf.Broker.String())
+						isFatal(err)
+						defer client.Close()
+
+						task, err := client.ReplayDeadLetter(context.Background(), sink, letter, c.Int("retries"))
+						isFatal(err)
+						fmt.Println(task.String())
+						return nil
 					},
 				},
 			},
@@ -373,8 +456,9 @@ func main() {
 					Action: func(c *cli.Context) error {
 						client, err := zbc.NewClient(conf.Broker.String())
 						isFatal(err)
+						defer client.Close()
 
-						topology, err := client.Topology()
+						topology, err := client.Topology(context.Background())
 						isFatal(err)
 
 						w := tabwriter.NewWriter(os.Stdout, 0, 0, 10, ' ', tabwriter.TabIndent)
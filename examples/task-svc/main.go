@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/zeebe-io/zbc-go/zbc"
 	"log"
 	"net/http"
+	"os"
+	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc"
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
 )
 
 var (
@@ -17,100 +22,63 @@ var (
 
 const BrokerAddr = "0.0.0.0:51015"
 
-type StopCh chan bool
+var (
+	client    *zbc.Client
+	workersMu sync.Mutex
+	workers   map[string]*zbc.TaskWorker
+)
 
-var workers map[string]StopCh
+func processTask(ctx context.Context, task *zbmsgpack.Task) (interface{}, error) {
+	atomic.AddUint64(&ProcessedEventsCount, 1)
+	return nil, nil
+}
 
-func processTask(lo string, msg *zbc.SubscriptionEvent) {
-	log.Printf("[%s] Working on task.\n", lo)
+func countError(task *zbmsgpack.Task, err error) {
+	log.Println("Completing a task went wrong.")
+	log.Println(err)
+	atomic.AddUint64(&ErrorCount, 1)
 }
 
-func openSubscription(client *zbc.Client, stopCh chan bool, topic string, lo string, tt string) {
-	subscriptionCh, subInfo, err := client.TaskConsumer(topic, lo, tt)
+func writeJSON(w http.ResponseWriter, resp map[string]interface{}) {
+	jsonResp, err := json.MarshalIndent(resp, "", "    ")
 	if err != nil {
-		atomic.AddUint64(&ErrorCount, 1)
-	}
-
-	credits := subInfo.Credits
-
-	log.Println("Subscription opened with", credits, "Credits")
-	log.Println("Waiting for events ....")
-	for {
-		select {
-		case message := <-subscriptionCh:
-			credits--
-
-			processTask(lo, message)
-			response, err := client.CompleteTask(message)
-
-			if err != nil {
-				log.Println("Completing a task went wrong.")
-				log.Println(err)
-			}
-
-			if response.State == zbc.TaskCompleted {
-				atomic.AddUint64(&ProcessedEventsCount, 1)
-				log.Println("Task completed successfully.")
-			} else {
-				log.Println("Task not completed.")
-			}
-
-			if credits < 1 {
-				response, err := client.IncreaseTaskSubscriptionCredits(subInfo)
-
-				if err != nil {
-					log.Println("Increasing task credits went wrong.")
-					log.Println(err)
-
-				} else {
-					credits = response.Credits
-					log.Println("Increased task credits to", credits)
-				}
-			}
-
-			break
-
-		case stop := <-stopCh:
-			if stop {
-				log.Print("Stopping worker.")
-				_, err := client.CloseTaskSubscription(subInfo)
-				if err != nil {
-					log.Println("close task subscription request failed")
-					log.Println(err)
-				}
-				log.Println("Gracefully shutting down the client.")
-				client.close()
-				return
-			}
-			break
-		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+	w.Write(jsonResp)
 }
 
 func startWorkerView(w http.ResponseWriter, r *http.Request) {
 	resp := make(map[string]interface{})
-	// TODO: fix multiple clients ...
-	zbClient, err := zbc.NewClient(BrokerAddr)
-	if err != nil {
-		resp["status"] = http.StatusInternalServerError
-	}
-	resp["status"] = http.StatusOK
 	lockOwner := fmt.Sprintf("zbc-%s", time.Now().Format("20060102150405"))
 
-	log.Printf("Starting worker with ID: %s\n", lockOwner)
-	workers[lockOwner] = make(chan bool)
-	go openSubscription(zbClient, workers[lockOwner], "default-topic", lockOwner, "foo")
-
-	resp["workerID"] = lockOwner
-	jsonResp, err := json.Marshal(resp)
+	worker, err := client.NewTaskWorker("default-topic", "foo", zbc.TaskHandlerFunc(processTask),
+		zbc.WithWorkerLockOwner(lockOwner),
+		zbc.WithWorkerMiddleware(zbc.RecoveryMiddleware(), zbc.LoggingMiddleware(log.New(os.Stderr, "", log.LstdFlags))),
+		zbc.WithWorkerErrorHandler(countError))
+	if err == nil {
+		err = worker.Start(context.Background())
+	}
 	if err != nil {
+		atomic.AddUint64(&ErrorCount, 1)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	w.Write(jsonResp)
+	log.Printf("Started worker with ID: %s\n", lockOwner)
+	workersMu.Lock()
+	workers[lockOwner] = worker
+	workersMu.Unlock()
+
+	resp["status"] = http.StatusOK
+	resp["workerID"] = lockOwner
+	writeJSON(w, resp)
 }
 
 func statsView(w http.ResponseWriter, r *http.Request) {
+	workersMu.Lock()
+	defer workersMu.Unlock()
+
 	resp := make(map[string]interface{})
 	resp["Running"] = true
 	if len(workers) == 0 {
@@ -121,37 +89,44 @@ func statsView(w http.ResponseWriter, r *http.Request) {
 		resp["ErrorCount"] = atomic.LoadUint64(&ErrorCount)
 
 		var workersIDs []string
-		for key, _ := range workers {
+		for key := range workers {
 			workersIDs = append(workersIDs, key)
 		}
 		resp["WorkersIDs"] = workersIDs
 	}
-	jsonResp, err := json.MarshalIndent(resp, "", "    ")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-
-	w.Write(jsonResp)
+	writeJSON(w, resp)
 }
 
 func stopWorkersView(w http.ResponseWriter, r *http.Request) {
-	resp := make(map[string]interface{})
-	for _, worker := range workers {
-		worker <- true
-	}
-	workers = make(map[string]StopCh)
-	resp["Status"] = http.StatusOK
+	workersMu.Lock()
+	defer workersMu.Unlock()
 
-	jsonResp, err := json.MarshalIndent(resp, "", "    ")
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for lockOwner, worker := range workers {
+		log.Printf("Stopping worker %s.\n", lockOwner)
+		if err := worker.Stop(ctx); err != nil {
+			log.Println("Stopping worker went wrong.")
+			log.Println(err)
+		}
 	}
+	workers = make(map[string]*zbc.TaskWorker)
 
-	w.Write(jsonResp)
+	resp := make(map[string]interface{})
+	resp["Status"] = http.StatusOK
+	writeJSON(w, resp)
 }
 
 func main() {
-	workers = make(map[string]StopCh)
+	var err error
+	client, err = zbc.NewClient(BrokerAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Close()
+
+	workers = make(map[string]*zbc.TaskWorker)
 	log.Println("Super microservice started.")
 	log.Println("Waiting for workers to start.")
 
@@ -1,6 +1,7 @@
 package testbroker
 
 import (
+	"context"
 	"github.com/zeebe-io/zbc-go/zbc"
 	"testing"
 )
@@ -9,14 +10,15 @@ func TestCreateTopic(t *testing.T) {
 	zbClient, err := zbc.NewClient(brokerAddr)
 	assert(t, nil, err, true)
 	assert(t, nil, zbClient, false)
+	defer zbClient.Close()
 
 	hash := RandStringBytes(25)
-	topic, err := zbClient.CreateTopic(hash, 3)
+	topic, err := zbClient.CreateTopic(context.Background(), hash, 3)
 	assert(t, nil, err, true)
 	assert(t, nil, topic, false)
 
 	assert(t, zbc.TopicCreated, topic.State, true)
 
-	topic, _ = zbClient.CreateTopic("default-topic", 3)
+	topic, _ = zbClient.CreateTopic(context.Background(), "default-topic", 3)
 	assert(t, nil, topic, false)
 }
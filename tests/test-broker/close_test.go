@@ -0,0 +1,27 @@
+package testbroker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zeebe-io/zbc-go/zbc"
+)
+
+func TestClose(t *testing.T) {
+	zbClient, err := zbc.NewClient(brokerAddr)
+	assert(t, nil, err, true)
+	assert(t, nil, zbClient, false)
+
+	_, subscription, err := zbClient.TaskConsumer(context.Background(), topicName, "close_test", "foo")
+	assert(t, nil, err, true)
+	assert(t, nil, subscription, false)
+
+	err = zbClient.Close()
+	assert(t, nil, err, true)
+
+	_, err = zbClient.CreateTopic(context.Background(), RandStringBytes(25), 1)
+	assert(t, zbc.ErrClientClosed, err, true)
+
+	err = zbClient.Close()
+	assert(t, nil, err, true)
+}
@@ -1,6 +1,7 @@
 package testbroker
 
 import (
+	"context"
 	"github.com/zeebe-io/zbc-go/zbc"
 	"testing"
 )
@@ -10,14 +11,15 @@ func TestCreateInstance(t *testing.T) {
 
 	assert(t, nil, err, true)
 	assert(t, nil, zbClient, false)
+	defer zbClient.Close()
 
-	workflow, err := zbClient.CreateWorkflowFromFile(topicName, zbc.BpmnXml, "../../examples/demoProcess.bpmn")
+	workflow, err := zbClient.CreateWorkflowFromFile(context.Background(), topicName, zbc.BpmnXml, "../../examples/demoProcess.bpmn")
 	assert(t, nil, err, true)
 	assert(t, nil, workflow, false)
 	assert(t, zbc.DeploymentCreated, workflow.State, true)
 
 	instance := zbc.NewWorkflowInstance("demoProcess", -1, nil)
-	createdInstance, err := zbClient.CreateWorkflowInstance(topicName, instance)
+	createdInstance, err := zbClient.CreateWorkflowInstance(context.Background(), topicName, instance)
 	assert(t, nil, err, true)
 	assert(t, nil, createdInstance, false)
 	assert(t, zbc.WorkflowInstanceCreated, createdInstance.State, true)
@@ -1,6 +1,7 @@
 package testbroker
 
 import (
+	"context"
 	"github.com/zeebe-io/zbc-go/zbc"
 	"testing"
 )
@@ -9,9 +10,10 @@ func TestCreateTask(t *testing.T) {
 	zbClient, err := zbc.NewClient(brokerAddr)
 	assert(t, nil, err, true)
 	assert(t, nil, zbClient, false)
+	defer zbClient.Close()
 
 	task := zbc.NewTask("testType", "test-owner")
-	responseTask, err := zbClient.CreateTask(topicName, task)
+	responseTask, err := zbClient.CreateTask(context.Background(), topicName, task)
 	assert(t, nil, err, true)
 	assert(t, nil, responseTask, false)
 
@@ -1,6 +1,7 @@
 package testbroker
 
 import (
+	"context"
 	"github.com/zeebe-io/zbc-go/zbc"
 	"testing"
 )
@@ -9,8 +10,9 @@ func TestCreateWorkflow(t *testing.T) {
 	zbClient, err := zbc.NewClient(brokerAddr)
 	assert(t, nil, err, true)
 	assert(t, nil, zbClient, false)
+	defer zbClient.Close()
 
-	workflow, err := zbClient.CreateWorkflowFromFile(topicName, zbc.BpmnXml, "../../examples/demoProcess.bpmn")
+	workflow, err := zbClient.CreateWorkflowFromFile(context.Background(), topicName, zbc.BpmnXml, "../../examples/demoProcess.bpmn")
 	assert(t, nil, err, true)
 	assert(t, nil, workflow, false)
 	assert(t, zbc.DeploymentCreated, workflow.State, true)
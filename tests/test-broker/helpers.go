@@ -2,11 +2,14 @@ package testbroker
 
 import (
 	"errors"
+	"fmt"
 	"math/rand"
 	"reflect"
 	"runtime/debug"
 	"testing"
 	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
 )
 
 var errClientStartFailed = errors.New("cannot connect to the broker")
@@ -31,3 +34,13 @@ func RandStringBytes(n int) string {
 	}
 	return string(b)
 }
+
+// headerKey returns the key stored under name in the headers of the task.
+func headerKey(t *testing.T, task *zbmsgpack.Task, name string) uint64 {
+	var key uint64
+	if _, err := fmt.Sscan(fmt.Sprint(task.Headers[name]), &key); err != nil {
+		debug.PrintStack()
+		t.Fatalf("Task header '%s' is not a key: %v\n", name, task.Headers[name])
+	}
+	return key
+}
@@ -0,0 +1,63 @@
+package testbroker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/zeebe-io/zbc-go/zbc"
+)
+
+// incidentProcess maps a variable which the instance payload lacks, so every instance raises an incident.
+const incidentProcess = `name: %s
+
+tasks:
+    - id: Task_1
+      type: incident
+      inputs:
+          - source: $.orderId
+            target: $.orderId
+`
+
+func TestResolveIncident(t *testing.T) {
+	zbClient, err := zbc.NewClient(brokerAddr)
+	assert(t, nil, err, true)
+	assert(t, nil, zbClient, false)
+	defer zbClient.Close()
+
+	processID := "incident" + RandStringBytes(8)
+	resource := zbc.NewResource(processID+".yaml", zbc.YamlWorkflow, []byte(fmt.Sprintf(incidentProcess, processID)))
+	workflow, err := zbClient.CreateWorkflow(context.Background(), topicName, resource)
+	assert(t, nil, err, true)
+	assert(t, nil, workflow, false)
+	assert(t, zbc.DeploymentCreated, workflow.State, true)
+
+	instance := zbc.NewWorkflowInstance(processID, -1, map[string]interface{}{"a": "b"})
+	createdInstance, err := zbClient.CreateWorkflowInstance(context.Background(), topicName, instance)
+	assert(t, nil, err, true)
+	assert(t, zbc.WorkflowInstanceCreated, createdInstance.State, true)
+
+	incidentCh, subscription, err := zbClient.IncidentConsumer(context.Background(), topicName, "incident_test"+RandStringBytes(8), 0)
+	assert(t, nil, err, true)
+	assert(t, nil, subscription, false)
+
+	var incident *zbc.IncidentEvent
+	for incident == nil {
+		event := <-incidentCh
+		assert(t, nil, event, false)
+		if event.Incident.BPMNProcessID == processID && event.Incident.State == zbc.IncidentCreated {
+			incident = event
+		}
+	}
+
+	_, err = zbClient.ResolveIncident(context.Background(), topicName, incident, "o-1")
+	assert(t, nil, err, false)
+
+	resolved, err := zbClient.ResolveIncident(context.Background(), topicName, incident, map[string]interface{}{"orderId": "o-1"})
+	assert(t, nil, err, true)
+	assert(t, nil, resolved, false)
+	assert(t, zbc.IncidentResolved, resolved.State, true)
+
+	errs := zbClient.CloseTopicSubscription(context.Background(), subscription)
+	assert(t, 0, len(errs), true)
+}
@@ -1,6 +1,7 @@
 package testbroker
 
 import (
+	"context"
 	"testing"
 
 	"github.com/zeebe-io/zbc-go/zbc"
@@ -10,7 +11,8 @@ func TestGetPartitions(t *testing.T) {
 	zbClient, err := zbc.NewClient(brokerAddr)
 	assert(t, nil, err, true)
 	assert(t, nil, zbClient, false)
-	partitions, err := zbClient.GetPartitions()
+	defer zbClient.Close()
+	partitions, err := zbClient.GetPartitions(context.Background())
 
 	assert(t, nil, err, true)
 	assert(t, nil, partitions, false)
@@ -0,0 +1,50 @@
+package testbroker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zeebe-io/zbc-go/zbc"
+)
+
+func TestTaskCommands(t *testing.T) {
+	zbClient, err := zbc.NewClient(brokerAddr)
+	assert(t, nil, err, true)
+	assert(t, nil, zbClient, false)
+	defer zbClient.Close()
+
+	taskType := "task_command_test" + RandStringBytes(8)
+	task := zbc.NewTask(taskType, "test-owner")
+	task.Retries = 1
+	createdTask, err := zbClient.CreateTask(context.Background(), topicName, task)
+	assert(t, nil, err, true)
+	assert(t, zbc.TaskCreated, createdTask.State, true)
+
+	subscriptionCh, subscription, err := zbClient.TaskConsumer(context.Background(), topicName, "task_command_test", taskType)
+	assert(t, nil, err, true)
+	assert(t, nil, subscription, false)
+
+	message := <-subscriptionCh
+	failedTask, err := zbClient.FailTask(context.Background(), message)
+	assert(t, nil, err, true)
+	assert(t, nil, failedTask, false)
+	assert(t, zbc.TaskFailed, failedTask.State, true)
+	assert(t, 0, failedTask.Retries, true)
+	assert(t, 1, message.Task.Retries, true)
+
+	updatedTask, err := zbClient.UpdateTaskRetries(context.Background(), message, 2)
+	assert(t, nil, err, true)
+	assert(t, nil, updatedTask, false)
+	assert(t, zbc.TaskRetriesUpdated, updatedTask.State, true)
+	assert(t, 2, updatedTask.Retries, true)
+
+	// With retries the task is locked by the subscription again.
+	message = <-subscriptionCh
+	canceledTask, err := zbClient.CancelTask(context.Background(), message)
+	assert(t, nil, err, true)
+	assert(t, nil, canceledTask, false)
+	assert(t, zbc.TaskCanceled, canceledTask.State, true)
+
+	errs := zbClient.CloseTaskSubscription(context.Background(), subscription)
+	assert(t, 0, len(errs), true)
+}
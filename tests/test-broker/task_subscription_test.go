@@ -1,6 +1,7 @@
 package testbroker
 
 import (
+	"context"
 	"testing"
 
 	"github.com/zeebe-io/zbc-go/zbc"
@@ -10,8 +11,9 @@ func TestTaskSubscription(t *testing.T) {
 	zbClient, err := zbc.NewClient(brokerAddr)
 	assert(t, nil, err, true)
 	assert(t, nil, zbClient, false)
+	defer zbClient.Close()
 
-	workflow, err := zbClient.CreateWorkflowFromFile(topicName, zbc.BpmnXml, "../../examples/demoProcess.bpmn")
+	workflow, err := zbClient.CreateWorkflowFromFile(context.Background(), topicName, zbc.BpmnXml, "../../examples/demoProcess.bpmn")
 	assert(t, nil, err, true)
 	assert(t, nil, workflow, false)
 	assert(t, nil, workflow.State, false)
@@ -21,22 +23,22 @@ func TestTaskSubscription(t *testing.T) {
 	payload["a"] = "b"
 
 	instance := zbc.NewWorkflowInstance("demoProcess", -1, payload)
-	createdInstance, err := zbClient.CreateWorkflowInstance(topicName, instance)
+	createdInstance, err := zbClient.CreateWorkflowInstance(context.Background(), topicName, instance)
 	assert(t, nil, err, true)
 	assert(t, nil, createdInstance, false)
 	assert(t, zbc.WorkflowInstanceCreated, createdInstance.State, true)
 
-	subscriptionCh, subscription, err := zbClient.TaskConsumer(topicName, "task_subscription_test", "foo")
+	subscriptionCh, subscription, err := zbClient.TaskConsumer(context.Background(), topicName, "task_subscription_test", "foo")
 	assert(t, nil, err, true)
 	assert(t, nil, subscription, false)
 	assert(t, nil, subscriptionCh, false)
 
 	message := <-subscriptionCh
 
-	response, err := zbClient.CompleteTask(message)
+	response, err := zbClient.CompleteTask(context.Background(), message)
 	assert(t, nil, err, true)
 	assert(t, nil, response, false)
 
-	errs := zbClient.CloseTaskSubscription(subscription)
+	errs := zbClient.CloseTaskSubscription(context.Background(), subscription)
 	assert(t, 0, len(errs), true)
 }
@@ -1,6 +1,7 @@
 package testbroker
 
 import (
+	"context"
 	"testing"
 
 	"github.com/zeebe-io/zbc-go/zbc"
@@ -10,8 +11,9 @@ func TestTopicSubscription(t *testing.T) {
 	zbClient, err := zbc.NewClient(brokerAddr)
 	assert(t, nil, err, true)
 	assert(t, nil, zbClient, false)
+	defer zbClient.Close()
 
-	workflow, err := zbClient.CreateWorkflowFromFile(topicName, zbc.BpmnXml, "../../examples/demoProcess.bpmn")
+	workflow, err := zbClient.CreateWorkflowFromFile(context.Background(), topicName, zbc.BpmnXml, "../../examples/demoProcess.bpmn")
 	assert(t, nil, err, true)
 	assert(t, nil, workflow, false)
 	assert(t, zbc.DeploymentCreated, workflow.State, true)
@@ -22,13 +24,13 @@ func TestTopicSubscription(t *testing.T) {
 	instance := zbc.NewWorkflowInstance("demoProcess", -1, payload)
 
 	for i := 0; i < 3; i++ {
-		createdInstance, err := zbClient.CreateWorkflowInstance(topicName, instance)
+		createdInstance, err := zbClient.CreateWorkflowInstance(context.Background(), topicName, instance)
 		assert(t, nil, err, true)
 		assert(t, nil, createdInstance, false)
 		assert(t, zbc.WorkflowInstanceCreated, createdInstance.State, true)
 	}
 
-	subscriptionCh, subscription, err := zbClient.TopicConsumer(topicName, "default-name", 0)
+	subscriptionCh, subscription, err := zbClient.TopicConsumer(context.Background(), topicName, "default-name", 0)
 	assert(t, nil, err, true)
 	assert(t, nil, subscription, false)
 	assert(t, nil, subscriptionCh, false)
@@ -39,6 +41,6 @@ func TestTopicSubscription(t *testing.T) {
 		assert(t, nil, message, false)
 	}
 
-	errs := zbClient.CloseTopicSubscription(subscription)
+	errs := zbClient.CloseTopicSubscription(context.Background(), subscription)
 	assert(t, 0, len(errs), true)
 }
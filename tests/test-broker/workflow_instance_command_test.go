@@ -0,0 +1,55 @@
+package testbroker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zeebe-io/zbc-go/zbc"
+)
+
+func TestWorkflowInstanceCommands(t *testing.T) {
+	zbClient, err := zbc.NewClient(brokerAddr)
+	assert(t, nil, err, true)
+	assert(t, nil, zbClient, false)
+	defer zbClient.Close()
+
+	workflow, err := zbClient.CreateWorkflowFromFile(context.Background(), topicName, zbc.BpmnXml, "../../examples/demoProcess.bpmn")
+	assert(t, nil, err, true)
+	assert(t, nil, workflow, false)
+	assert(t, zbc.DeploymentCreated, workflow.State, true)
+
+	payload := make(map[string]interface{})
+	payload["a"] = "b"
+
+	instance := zbc.NewWorkflowInstance("demoProcess", -1, payload)
+	createdInstance, err := zbClient.CreateWorkflowInstance(context.Background(), topicName, instance)
+	assert(t, nil, err, true)
+	assert(t, nil, createdInstance, false)
+	assert(t, zbc.WorkflowInstanceCreated, createdInstance.State, true)
+
+	subscriptionCh, subscription, err := zbClient.TaskConsumer(context.Background(), topicName, "workflow_instance_command_test", "foo")
+	assert(t, nil, err, true)
+	assert(t, nil, subscription, false)
+
+	// Task headers carry the keys of its activity and workflow instance, both live on the partition of the task.
+	message := <-subscriptionCh
+	partitionID := message.Event.PartitionId
+	activityInstanceKey := headerKey(t, message.Task, "activityInstanceKey")
+	workflowInstanceKey := headerKey(t, message.Task, "workflowInstanceKey")
+
+	_, err = zbClient.UpdateWorkflowInstancePayload(context.Background(), topicName, partitionID, activityInstanceKey, 42)
+	assert(t, nil, err, false)
+
+	updated, err := zbClient.UpdateWorkflowInstancePayload(context.Background(), topicName, partitionID, activityInstanceKey, map[string]interface{}{"c": "d"})
+	assert(t, nil, err, true)
+	assert(t, nil, updated, false)
+	assert(t, zbc.WorkflowInstancePayloadUpdated, updated.State, true)
+
+	canceled, err := zbClient.CancelWorkflowInstance(context.Background(), topicName, partitionID, workflowInstanceKey)
+	assert(t, nil, err, true)
+	assert(t, nil, canceled, false)
+	assert(t, zbc.WorkflowInstanceCanceled, canceled.State, true)
+
+	errs := zbClient.CloseTaskSubscription(context.Background(), subscription)
+	assert(t, 0, len(errs), true)
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/zeebe-io/zbc-go/zbc"
+	"github.com/zeebe-io/zbc-go/zbc/zbsbe"
 )
 
 const (
@@ -249,6 +250,14 @@ func TestErrorTopicNotFound(t *testing.T) {
 	if message == nil {
 		t.Fatalf("Message is nil.")
 	}
+
+	errorResponse, ok := (*message.SbeMessage).(*zbsbe.ErrorResponse)
+	if !ok {
+		t.Fatalf("Message is not an error response.")
+	}
+	if errorResponse.ErrorCode != zbsbe.ErrorCode.PARTITION_NOT_FOUND {
+		t.Fatalf("Unexpected error code %d.", errorResponse.ErrorCode)
+	}
 }
 
 //func TestKeepAlive(t *testing.T) {
@@ -0,0 +1,58 @@
+package zbc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+)
+
+// batchWindowSize is the number of batch items which are in flight at once.
+const batchWindowSize = 512
+
+// TaskResult holds the outcome of a single item of CreateTasks.
+type TaskResult struct {
+	Task *zbmsgpack.Task
+	Err  error
+}
+
+// WorkflowInstanceResult holds the outcome of a single item of CreateWorkflowInstances.
+type WorkflowInstanceResult struct {
+	WorkflowInstance *zbmsgpack.WorkflowInstance
+	Err              error
+}
+
+// runBatch will call op for every index in [0, n) with at most batchWindowSize calls running at once.
+func runBatch(n int, op func(i int)) {
+	var wg sync.WaitGroup
+	window := make(chan struct{}, batchWindowSize)
+
+	for i := 0; i < n; i++ {
+		window <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-window
+				wg.Done()
+			}()
+			op(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (rm *requestManager) createTasks(ctx context.Context, topic string, tasks []*zbmsgpack.Task) []TaskResult {
+	results := make([]TaskResult, len(tasks))
+	runBatch(len(tasks), func(i int) {
+		results[i].Task, results[i].Err = rm.createTask(ctx, topic, tasks[i])
+	})
+	return results
+}
+
+func (rm *requestManager) createWorkflowInstances(ctx context.Context, topic string, instances []*zbmsgpack.WorkflowInstance) []WorkflowInstanceResult {
+	results := make([]WorkflowInstanceResult, len(instances))
+	runBatch(len(instances), func(i int) {
+		results[i].WorkflowInstance, results[i].Err = rm.createWorkflowInstance(ctx, topic, instances[i])
+	})
+	return results
+}
@@ -0,0 +1,104 @@
+package zbc
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+)
+
+func TestRunBatchResults(t *testing.T) {
+	errOdd := errors.New("odd item")
+	tasks := make([]*zbmsgpack.Task, 100)
+	for i := range tasks {
+		tasks[i] = &zbmsgpack.Task{Retries: i}
+	}
+
+	// Stub executor which finishes the items out of order and fails every odd one.
+	execute := func(task *zbmsgpack.Task) (*zbmsgpack.Task, error) {
+		time.Sleep(time.Duration(len(tasks)-task.Retries) * 10 * time.Microsecond)
+		if task.Retries%2 == 1 {
+			return nil, errOdd
+		}
+		return task, nil
+	}
+
+	results := make([]TaskResult, len(tasks))
+	runBatch(len(tasks), func(i int) {
+		results[i].Task, results[i].Err = execute(tasks[i])
+	})
+
+	for i, result := range results {
+		if i%2 == 1 {
+			if result.Err != errOdd || result.Task != nil {
+				t.Fatalf("item %d: expected errOdd, got %v", i, result)
+			}
+			continue
+		}
+		if result.Err != nil || result.Task != tasks[i] {
+			t.Fatalf("item %d: expected its own task, got %v", i, result)
+		}
+	}
+}
+
+func TestRunBatchWindow(t *testing.T) {
+	var inFlight, maxInFlight, calls int64
+	release := make(chan struct{})
+	full := make(chan struct{})
+	var fullOnce sync.Once
+
+	done := make(chan struct{})
+	go func() {
+		runBatch(2*batchWindowSize+1, func(i int) {
+			n := atomic.AddInt64(&inFlight, 1)
+			atomic.AddInt64(&calls, 1)
+			for {
+				max := atomic.LoadInt64(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt64(&maxInFlight, max, n) {
+					break
+				}
+			}
+			if n == batchWindowSize {
+				fullOnce.Do(func() { close(full) })
+			}
+			<-release
+			atomic.AddInt64(&inFlight, -1)
+		})
+		close(done)
+	}()
+
+	select {
+	case <-full:
+	case <-time.After(5 * time.Second):
+		t.Fatal("window wasn't filled")
+	}
+
+	// Nothing beyond the window is started while it is full.
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt64(&calls); n != batchWindowSize {
+		t.Fatalf("expected %d calls in flight, got %d", batchWindowSize, n)
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch didn't finish")
+	}
+
+	if n := atomic.LoadInt64(&calls); n != 2*batchWindowSize+1 {
+		t.Fatalf("expected %d calls, got %d", 2*batchWindowSize+1, n)
+	}
+	if max := atomic.LoadInt64(&maxInFlight); max > batchWindowSize {
+		t.Fatalf("expected at most %d calls in flight, got %d", batchWindowSize, max)
+	}
+}
+
+func TestRunBatchEmpty(t *testing.T) {
+	runBatch(0, func(i int) {
+		t.Fatal("op called for an empty batch")
+	})
+}
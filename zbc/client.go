@@ -1,11 +1,12 @@
 package zbc
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
 	"path/filepath"
+	"reflect"
 
-	"github.com/vmihailenco/msgpack"
 	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
 )
 
@@ -14,25 +15,36 @@ var (
 	errSocketWrite         = errors.New("tried to write more bytes to socket")
 	errTopicLeaderNotFound = errors.New("topic leader not found")
 	errResourceNotFound    = errors.New("resource not found")
+	errInvalidRetries      = errors.New("retries must be positive")
+	errNoIncident          = errors.New("incident event is required")
+	errNoWorkflowInstance  = errors.New("workflow instance is required")
+
+	// ErrClientClosed is returned for every request which is pending or issued after the client was closed.
+	ErrClientClosed = errors.New("client closed")
+
+	// ErrTaskLockExpired is returned when a task is completed after the deadline of its lock passed.
+	ErrTaskLockExpired = errors.New("task lock expired")
 )
 
 // Client for Zeebe broker with support for clustered deployment.
+// Every method which talks to the broker accepts a context.Context. If the context has no deadline, RequestTimeout is applied.
+// When the broker rejects a command, the rejected event is returned together with a *RejectionError.
 type Client struct {
 	*requestManager
 }
 
 // CreateTask will create new task on specified topic.
-func (c *Client) CreateTask(topic string, task *zbmsgpack.Task) (*zbmsgpack.Task, error) {
-	return c.createTask(topic, task)
+func (c *Client) CreateTask(ctx context.Context, topic string, task *zbmsgpack.Task) (*zbmsgpack.Task, error) {
+	return c.createTask(ctx, topic, task)
 }
 
 // CreateWorkflow will deploy process to the broker.
-func (c *Client) CreateWorkflow(topic string, resources ...*zbmsgpack.Resource) (*zbmsgpack.Workflow, error) {
-	return c.createWorkflow(topic, resources)
+func (c *Client) CreateWorkflow(ctx context.Context, topic string, resources ...*zbmsgpack.Resource) (*zbmsgpack.Workflow, error) {
+	return c.createWorkflow(ctx, topic, resources)
 }
 
 // CreateWorkflowFromFile will read workflow file and return message pack workflow object.
-func (c *Client) CreateWorkflowFromFile(topic, resourceType, path string) (*zbmsgpack.Workflow, error) {
+func (c *Client) CreateWorkflowFromFile(ctx context.Context, topic, resourceType, path string) (*zbmsgpack.Workflow, error) {
 	if len(path) == 0 {
 		return nil, errResourceNotFound
 	}
@@ -43,57 +55,200 @@ func (c *Client) CreateWorkflowFromFile(topic, resourceType, path string) (*zbms
 	if err != nil {
 		return nil, errResourceNotFound
 	}
-	return c.CreateWorkflow(topic, resource)
+	return c.CreateWorkflow(ctx, topic, resource)
 }
 
 // CreateWorkflowInstance will create new workflow instance on the broker.
-func (c *Client) CreateWorkflowInstance(topic string, workflowInstance *zbmsgpack.WorkflowInstance) (*zbmsgpack.WorkflowInstance, error) {
-	return c.createWorkflowInstance(topic, workflowInstance)
+func (c *Client) CreateWorkflowInstance(ctx context.Context, topic string, workflowInstance *zbmsgpack.WorkflowInstance) (*zbmsgpack.WorkflowInstance, error) {
+	return c.createWorkflowInstance(ctx, topic, workflowInstance)
+}
+
+// CreateTaskAsync will send the create task command without waiting for the response. The ctx bounds the lifetime of the request.
+func (c *Client) CreateTaskAsync(ctx context.Context, topic string, task *zbmsgpack.Task) *TaskFuture {
+	return &TaskFuture{newRequestFuture(func() (interface{}, error) {
+		return c.createTask(ctx, topic, task)
+	})}
+}
+
+// CreateWorkflowInstanceAsync will send the create workflow instance command without waiting for the response. The ctx bounds the lifetime of the request.
+func (c *Client) CreateWorkflowInstanceAsync(ctx context.Context, topic string, workflowInstance *zbmsgpack.WorkflowInstance) *WorkflowInstanceFuture {
+	return &WorkflowInstanceFuture{newRequestFuture(func() (interface{}, error) {
+		return c.createWorkflowInstance(ctx, topic, workflowInstance)
+	})}
+}
+
+// CreateTasks will create all the tasks on specified topic, spread across its partitions. Requests are pipelined and one
+// result per task is returned in the order of tasks.
+func (c *Client) CreateTasks(ctx context.Context, topic string, tasks []*zbmsgpack.Task) []TaskResult {
+	return c.createTasks(ctx, topic, tasks)
+}
+
+// CreateWorkflowInstances will create all the workflow instances on specified topic, spread across its partitions.
+// Requests are pipelined and one result per instance is returned in the order of instances.
+func (c *Client) CreateWorkflowInstances(ctx context.Context, topic string, instances []*zbmsgpack.WorkflowInstance) []WorkflowInstanceResult {
+	return c.createWorkflowInstances(ctx, topic, instances)
+}
+
+// CancelWorkflowInstance will cancel the workflow instance with the given key on the partition of the topic. Keys are
+// only unique within a partition, the partition is found in the PartitionId of the instance's events.
+func (c *Client) CancelWorkflowInstance(ctx context.Context, topic string, partitionID uint16, instanceKey uint64) (*zbmsgpack.WorkflowInstance, error) {
+	return c.cancelWorkflowInstance(ctx, topic, partitionID, instanceKey)
+}
+
+// UpdateWorkflowInstancePayload will replace the payload of the activity instance with the given key on the partition
+// of the topic. Payload is encoded as message pack document, so it must be a map or a struct.
+func (c *Client) UpdateWorkflowInstancePayload(ctx context.Context, topic string, partitionID uint16, activityInstanceKey uint64, payload interface{}) (*zbmsgpack.WorkflowInstance, error) {
+	return c.updateWorkflowInstancePayload(ctx, topic, partitionID, activityInstanceKey, payload)
 }
 
 // TaskConsumer opens a subscription on task and returns a channel where all the SubscribedEvents will arrive.
-func (c *Client) TaskConsumer(topic, lockOwner, taskType string) (chan *SubscriptionEvent, *zbmsgpack.TaskSubscriptionInfo, error) {
-	return c.taskConsumer(topic, lockOwner, taskType, 32)
+// The context only bounds opening of the subscription, use CloseTaskSubscription to tear it down.
+// Credits are replenished automatically as tasks are received from the channel, see WithCreditThreshold.
+func (c *Client) TaskConsumer(ctx context.Context, topic, lockOwner, taskType string) (chan *SubscriptionEvent, *zbmsgpack.TaskSubscriptionInfo, error) {
+	return c.taskConsumer(ctx, topic, lockOwner, taskType, c.config.lockDuration, c.config.creditConfig(c.config.taskCredits, nil))
+}
+
+// CompleteTask will notify broker about finished task. ErrTaskLockExpired is returned without contacting the broker
+// when the deadline of the task passed.
+func (c *Client) CompleteTask(ctx context.Context, task *SubscriptionEvent) (*zbmsgpack.Task, error) {
+	return c.completeTask(ctx, task)
+}
+
+// CompleteTaskWithPayload will notify broker about finished task and replace its payload with the given one. Payload is
+// encoded as message pack document.
+func (c *Client) CompleteTaskWithPayload(ctx context.Context, task *SubscriptionEvent, payload interface{}) (*zbmsgpack.Task, error) {
+	return c.completeTaskWithPayload(ctx, task, payload, false)
 }
 
-// CompleteTask will notify broker about finished task.
-func (c *Client) CompleteTask(task *SubscriptionEvent) (*zbmsgpack.Task, error) {
-	return c.completeTask(task)
+// CompleteTaskWithMergedPayload will notify broker about finished task after deep merging the given payload into the
+// payload of the task.
+func (c *Client) CompleteTaskWithMergedPayload(ctx context.Context, task *SubscriptionEvent, payload interface{}) (*zbmsgpack.Task, error) {
+	return c.completeTaskWithPayload(ctx, task, payload, true)
 }
 
-// IncreaseTaskSubscriptionCredits will increase the current credits of the task subscription.
-func (c *Client) IncreaseTaskSubscriptionCredits(task *zbmsgpack.TaskSubscription) (*zbmsgpack.TaskSubscription, error) {
-	return c.increaseTaskSubscriptionCredits(task)
+// CompleteTaskAsync will send the complete task command without waiting for the response. The ctx bounds the lifetime of the request.
+func (c *Client) CompleteTaskAsync(ctx context.Context, task *SubscriptionEvent) *TaskFuture {
+	return &TaskFuture{newRequestFuture(func() (interface{}, error) {
+		return c.completeTask(ctx, task)
+	})}
+}
+
+// FailTask will notify broker that the task couldn't be processed. Retries of the task are decreased by one, when
+// they reach zero the broker raises an incident and the task is stored in the dead-letter sink, if one is set.
+func (c *Client) FailTask(ctx context.Context, task *SubscriptionEvent) (*zbmsgpack.Task, error) {
+	return c.failTask(ctx, task)
+}
+
+// FailTaskWithError will fail the task like FailTask and store the message of cause in the custom header
+// TaskErrorMessageHeader, so that it can be seen when the task is inspected.
+func (c *Client) FailTaskWithError(ctx context.Context, task *SubscriptionEvent, cause error) (*zbmsgpack.Task, error) {
+	return c.failTaskWithError(ctx, task, cause)
+}
+
+// ReplayDeadLetter will set the retries of the dead-lettered task, so it can be locked by a subscription again, and
+// remove it from sink.
+func (c *Client) ReplayDeadLetter(ctx context.Context, sink DeadLetterSink, letter *DeadLetter, retries int) (*zbmsgpack.Task, error) {
+	return c.replayDeadLetter(ctx, sink, letter, retries)
+}
+
+// UpdateTaskRetries will set the retries of a failed task, so it can be locked by a subscription again.
+func (c *Client) UpdateTaskRetries(ctx context.Context, task *SubscriptionEvent, retries int) (*zbmsgpack.Task, error) {
+	return c.updateTaskRetries(ctx, task, retries)
+}
+
+// CancelTask will cancel the task, so it won't be handed out to subscriptions anymore.
+func (c *Client) CancelTask(ctx context.Context, task *SubscriptionEvent) (*zbmsgpack.Task, error) {
+	return c.cancelTask(ctx, task)
+}
+
+// CreditState returns the credit state of every partition of the task subscription, or nil if it isn't open.
+func (c *Client) CreditState(sub *zbmsgpack.TaskSubscriptionInfo) []PartitionCredits {
+	return c.creditState(sub)
+}
+
+// SetTaskRateLimit changes the rate limit of tasks of taskType at runtime, see WithTaskRateLimit. A rate which isn't
+// positive removes the limit.
+func (c *Client) SetTaskRateLimit(taskType string, rate float64, burst int) {
+	c.taskLimits.set(taskType, rate, burst)
+}
+
+// SetCommandRateLimit changes the rate limit of commands on topic at runtime, see WithCommandRateLimit. A rate which
+// isn't positive removes the limit.
+func (c *Client) SetCommandRateLimit(topic string, rate float64, burst int) {
+	c.commandLimits.set(topic, rate, burst)
+}
+
+// TaskRateLimits returns the state of the rate limits of tasks by task type.
+func (c *Client) TaskRateLimits() map[string]RateLimitState {
+	return c.taskLimits.state()
+}
+
+// CommandRateLimits returns the state of the rate limits of commands by topic.
+func (c *Client) CommandRateLimits() map[string]RateLimitState {
+	return c.commandLimits.state()
+}
+
+// IncreaseTaskSubscriptionCredits will increase the current credits of the task subscription. Credits are replenished
+// automatically, so this is only needed to grant additional credits.
+func (c *Client) IncreaseTaskSubscriptionCredits(ctx context.Context, task *zbmsgpack.TaskSubscription) (*zbmsgpack.TaskSubscription, error) {
+	return c.increaseTaskSubscriptionCredits(ctx, task)
 }
 
 // CloseTaskSubscription will tear down currently active task subscription.
-func (c *Client) CloseTaskSubscription(task *zbmsgpack.TaskSubscriptionInfo) []error {
-	return c.closeTaskSubscription(task)
+func (c *Client) CloseTaskSubscription(ctx context.Context, task *zbmsgpack.TaskSubscriptionInfo) []error {
+	return c.closeTaskSubscription(ctx, task)
 }
 
 // CloseTopicSubscription will tear down currently active topic subscription.
-func (c *Client) CloseTopicSubscription(topicSub *zbmsgpack.TopicSubscriptionInfo) []error {
-	return c.closeTopicSubscription(topicSub)
+func (c *Client) CloseTopicSubscription(ctx context.Context, topicSub *zbmsgpack.TopicSubscriptionInfo) []error {
+	return c.closeTopicSubscription(ctx, topicSub)
 }
 
 // TopicSubscriptionAck will ACK received events from the broker.
-func (c *Client) TopicSubscriptionAck(ts *zbmsgpack.TopicSubscription, s *SubscriptionEvent) (*zbmsgpack.TopicSubscriptionAck, error) {
-	return c.topicSubscriptionAck(ts, s)
+func (c *Client) TopicSubscriptionAck(ctx context.Context, ts *zbmsgpack.TopicSubscription, s *SubscriptionEvent) (*zbmsgpack.TopicSubscriptionAck, error) {
+	return c.topicSubscriptionAck(ctx, ts, s)
 }
 
 // TopicConsumer opens a subscription on topic and returns a channel where all the SubscribedEvents will arrive.
-func (c *Client) TopicConsumer(topic, subName string, startPosition int64) (chan *SubscriptionEvent, *zbmsgpack.TopicSubscriptionInfo, error) {
-	return c.topicConsumer(topic, subName, startPosition)
+// The context only bounds opening of the subscription, use CloseTopicSubscription to tear it down.
+// Events are acknowledged in the background as configured by opts, see TopicConsumerOption.
+func (c *Client) TopicConsumer(ctx context.Context, topic, subName string, startPosition int64, opts ...TopicConsumerOption) (chan *SubscriptionEvent, *zbmsgpack.TopicSubscriptionInfo, error) {
+	config, err := newTopicConsumerConfig(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.topicConsumer(ctx, topic, subName, startPosition, config)
+}
+
+// HandleTopic opens a subscription on topic and hands every event to handler, one at a time. An event is acknowledged
+// after the handler returned, WithAckBatch can be used to acknowledge in batches. The context only bounds opening of
+// the subscription, use CloseTopicSubscription to tear it down.
+func (c *Client) HandleTopic(ctx context.Context, topic, subName string, startPosition int64, handler TopicEventHandler, opts ...TopicConsumerOption) (*zbmsgpack.TopicSubscriptionInfo, error) {
+	return c.handleTopic(ctx, topic, subName, startPosition, handler, opts)
+}
+
+// IncidentConsumer opens a subscription on topic and returns a channel where all the incident events will arrive.
+// Incidents are acknowledged once they are taken from the channel, opts configure acknowledging as for TopicConsumer.
+// Use CloseTopicSubscription to tear it down.
+func (c *Client) IncidentConsumer(ctx context.Context, topic, subName string, startPosition int64, opts ...TopicConsumerOption) (chan *IncidentEvent, *zbmsgpack.TopicSubscriptionInfo, error) {
+	return c.incidentConsumer(ctx, topic, subName, startPosition, opts)
+}
+
+// ResolveIncident will resolve the incident received from IncidentConsumer, retrying the failed operation with the new
+// payload. The command is sent to the partition of the incident. Payload is encoded as message pack document, so it
+// must be a map or a struct.
+func (c *Client) ResolveIncident(ctx context.Context, topic string, incident *IncidentEvent, payload interface{}) (*zbmsgpack.Incident, error) {
+	return c.resolveIncident(ctx, topic, incident, payload)
 }
 
 // CreateTopic will create new topic with specified number of partitions.
-func (c *Client) CreateTopic(name string, partitionNum int) (*zbmsgpack.Topic, error) {
-	return c.createTopic(name, partitionNum)
+func (c *Client) CreateTopic(ctx context.Context, name string, partitionNum int) (*zbmsgpack.Topic, error) {
+	return c.createTopic(ctx, name, partitionNum)
 }
 
 // GetPartitions will return all partitions and information to which topic they belong to.
-func (c *Client) GetPartitions() (*zbmsgpack.PartitionCollection, error) {
-	return c.partitionRequest()
+func (c *Client) GetPartitions(ctx context.Context) (*zbmsgpack.PartitionCollection, error) {
+	return c.partitionRequest(ctx)
 }
 
 // UnmarshalFromFile will read binary message from disk.
@@ -109,14 +264,42 @@ func (c *Client) UnmarshalFromFile(path string) (*Message, error) {
 }
 
 // Topology request will retrieve all information about the cluster.
-func (c *Client) Topology() (*zbmsgpack.ClusterTopology, error) {
-	return c.refreshTopology()
+func (c *Client) Topology(ctx context.Context) (*zbmsgpack.ClusterTopology, error) {
+	return c.refreshTopology(ctx)
+}
+
+// UnknownSubscriberEvents returns the number of subscribed events which were dropped because no open subscription of
+// the client matched their subscriber key or subscription type, e.g. events which were in flight while closing a subscription.
+func (c *Client) UnknownSubscriberEvents() uint64 {
+	return c.unknownSubscriberEventsCount()
+}
+
+// Close will close all open task and topic subscriptions, fail pending requests with ErrClientClosed and tear down
+// all goroutines and connections of the client. The first error which occurred while closing subscriptions is returned.
+func (c *Client) Close() error {
+	return c.close()
 }
 
-// NewClient is constructor for Client structure. It will resolve IP address and dial the provided tcp address.
-func NewClient(bootstrapAddr string) (*Client, error) {
+// NewClient is constructor for Client structure. It will resolve IP address, dial the provided tcp address and fetch
+// the cluster topology. Additional bootstrap brokers can be set with WithBootstrapBrokers. An error is returned when
+// an option is invalid or the topology cannot be fetched from any of the bootstrap brokers.
+func NewClient(bootstrapAddr string, opts ...ClientOption) (*Client, error) {
+	config := newClientConfig()
+	config.bootstrapAddrs = []string{bootstrapAddr}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
 	c := &Client{
-		newRequestManager(bootstrapAddr),
+		newRequestManager(config),
+	}
+
+	if _, err := c.initTopology(context.Background()); err != nil {
+		c.Close()
+		return nil, err
 	}
 	return c, nil
 }
@@ -134,18 +317,55 @@ func NewTask(typeName, lockOwner string) *zbmsgpack.Task {
 	}
 }
 
-// NewWorkflowInstance will create new workflow instance.
+// NewWorkflowInstance will create new workflow instance. The payload, if any, is encoded as message pack document and
+// used as payload of the instance. A nil or empty payload leaves the instance without payload. It returns nil if more
+// than one payload is given or the payload isn't a map or a struct, use NewWorkflowInstanceWithPayload to get the error.
 func NewWorkflowInstance(bpmnProcessID string, version int, payload ...interface{}) *zbmsgpack.WorkflowInstance {
-	b, err := msgpack.Marshal(payload)
+	if len(payload) > 1 {
+		return nil
+	}
+
+	var document interface{}
+	if len(payload) > 0 {
+		document = payload[0]
+	}
+	instance, err := NewWorkflowInstanceWithPayload(bpmnProcessID, version, document)
 	if err != nil {
 		return nil
 	}
-	return &zbmsgpack.WorkflowInstance{
+	return instance
+}
+
+// NewWorkflowInstanceWithPayload will create new workflow instance with payload encoded as message pack document. A nil
+// or empty payload leaves the instance without payload, every other payload must be a map or a struct.
+func NewWorkflowInstanceWithPayload(bpmnProcessID string, version int, payload interface{}) (*zbmsgpack.WorkflowInstance, error) {
+	instance := &zbmsgpack.WorkflowInstance{
 		State:         CreateWorkflowInstance,
 		BPMNProcessID: bpmnProcessID,
 		Version:       version,
-		Payload:       b,
 	}
+	if isEmptyPayload(payload) {
+		return instance, nil
+	}
+	if err := instance.SetPayload(payload); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
+// isEmptyPayload reports whether v is nil, a nil pointer or an empty map.
+func isEmptyPayload(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	case reflect.Map:
+		return value.Len() == 0
+	}
+	return false
 }
 
 // NewResource will create new message pack resource.
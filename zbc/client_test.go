@@ -0,0 +1,65 @@
+package zbc
+
+import (
+	"context"
+	"testing"
+)
+
+type instancePayload struct {
+	OrderID string `msgpack:"orderId"`
+}
+
+func TestNewWorkflowInstanceWithoutPayload(t *testing.T) {
+	var document map[string]interface{}
+	var order *instancePayload
+
+	for _, payload := range [][]interface{}{nil, {nil}, {document}, {map[string]interface{}{}}, {order}} {
+		instance := NewWorkflowInstance("demoProcess", -1, payload...)
+		if instance == nil {
+			t.Fatalf("no instance for payload %#v", payload)
+		}
+		if instance.Payload != nil {
+			t.Fatalf("unexpected payload %v for %#v", instance.Payload, payload)
+		}
+	}
+}
+
+func TestNewWorkflowInstancePayload(t *testing.T) {
+	instance := NewWorkflowInstance("demoProcess", -1, instancePayload{OrderID: "o-1"})
+	if instance == nil {
+		t.Fatal("no instance")
+	}
+
+	var out instancePayload
+	if err := instance.UnmarshalPayload(&out); err != nil {
+		t.Fatalf("UnmarshalPayload failed: %s", err)
+	}
+	if out.OrderID != "o-1" {
+		t.Fatalf("unexpected payload %+v", out)
+	}
+}
+
+func TestNewWorkflowInstanceInvalidPayload(t *testing.T) {
+	if _, err := NewWorkflowInstanceWithPayload("demoProcess", -1, 42); err == nil {
+		t.Fatal("expected error for scalar payload")
+	}
+
+	instance, err := NewWorkflowInstanceWithPayload("demoProcess", -1, nil)
+	if err != nil || instance == nil || instance.Payload != nil {
+		t.Fatalf("unexpected result %v, %v", instance, err)
+	}
+
+	// Instances which the broker would reject aren't built at all.
+	for _, payload := range [][]interface{}{{42}, {"o-1"}, {[]string{"o-1"}}, {instancePayload{OrderID: "o-1"}, instancePayload{OrderID: "o-2"}}} {
+		if instance := NewWorkflowInstance("demoProcess", -1, payload...); instance != nil {
+			t.Fatalf("unexpected instance %v for %#v", instance, payload)
+		}
+	}
+}
+
+func TestCreateWorkflowInstanceWithoutInstance(t *testing.T) {
+	rm := &requestManager{}
+	if _, err := rm.createWorkflowInstance(context.Background(), "default-topic", nil); err != errNoWorkflowInstance {
+		t.Fatalf("expected errNoWorkflowInstance, got %v", err)
+	}
+}
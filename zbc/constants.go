@@ -2,7 +2,7 @@ package zbc
 
 import "time"
 
-// RequestTimeout specifies default timeout for responder.
+// RequestTimeout specifies default timeout for responder in seconds. It is applied to requests whose context has no deadline.
 const RequestTimeout = 30
 
 // TopologyRefreshInterval defines time to live of refreshTopology object.
@@ -17,6 +17,7 @@ const (
 
 // Sbe template ID constants
 const (
+	templateIDErrorResponse          = 0
 	templateIDExecuteCommandRequest  = 20
 	templateIDExecuteCommandResponse = 21
 	templateIDControlMessageResponse = 11
@@ -40,8 +41,21 @@ const (
 	TaskCreate  = "CREATE"
 	TaskCreated = "CREATED"
 
-	TaskComplete  = "COMPLETE"
-	TaskCompleted = "COMPLETED"
+	TaskComplete         = "COMPLETE"
+	TaskCompleted        = "COMPLETED"
+	TaskCompleteRejected = "COMPLETE_REJECTED"
+
+	TaskFail         = "FAIL"
+	TaskFailed       = "FAILED"
+	TaskFailRejected = "FAIL_REJECTED"
+
+	TaskUpdateRetries         = "UPDATE_RETRIES"
+	TaskRetriesUpdated        = "RETRIES_UPDATED"
+	TaskUpdateRetriesRejected = "UPDATE_RETRIES_REJECTED"
+
+	TaskCancel         = "CANCEL"
+	TaskCanceled       = "CANCELED"
+	TaskCancelRejected = "CANCEL_REJECTED"
 
 	CreateDeployment   = "CREATE"
 	DeploymentCreated  = "CREATED"
@@ -50,6 +64,14 @@ const (
 	CreateWorkflowInstance   = "CREATE_WORKFLOW_INSTANCE"
 	WorkflowInstanceCreated  = "WORKFLOW_INSTANCE_CREATED"
 	WorkflowInstanceRejected = "WORKFLOW_INSTANCE_REJECTED"
+
+	CancelWorkflowInstance         = "CANCEL_WORKFLOW_INSTANCE"
+	WorkflowInstanceCanceled       = "WORKFLOW_INSTANCE_CANCELED"
+	CancelWorkflowInstanceRejected = "CANCEL_WORKFLOW_INSTANCE_REJECTED"
+
+	UpdateWorkflowInstancePayload         = "UPDATE_PAYLOAD"
+	WorkflowInstancePayloadUpdated        = "PAYLOAD_UPDATED"
+	UpdateWorkflowInstancePayloadRejected = "UPDATE_PAYLOAD_REJECTED"
 )
 
 //
@@ -59,6 +81,19 @@ const (
 	TopicRejected = "CREATE_REJECTED"
 )
 
+// Incident states
+const (
+	IncidentCreated         = "CREATED"
+	IncidentResolve         = "RESOLVE"
+	IncidentResolved        = "RESOLVED"
+	IncidentResolveRejected = "RESOLVE_REJECTED"
+	IncidentResolveFailed   = "RESOLVE_FAILED"
+	IncidentDeleted         = "DELETED"
+)
+
+// Every state of a rejected command ends with this suffix.
+const rejectedStateSuffix = "REJECTED"
+
 // TopicSubscription states
 const (
 	TopicSubscriptionSubscribeState  = "SUBSCRIBE"
@@ -85,6 +120,32 @@ const (
 	SocketChunkSize = 4096
 )
 
+// Task subscription defaults
+const (
+	TaskSubscriptionCredits      = 32
+	TaskSubscriptionLockDuration = 5 * time.Minute
+	TaskLockSafetyMargin         = time.Second
+)
+
+// Task worker defaults
+const (
+	TaskWorkerConcurrency = 4
+	TaskWorkerLockOwner   = "zbc-go"
+)
+
+// DeadLetterMaxLineSize is the maximum size of a dead-lettered task in the file of FileDeadLetterSink.
+const DeadLetterMaxLineSize = 16 * 1024 * 1024
+
+// TaskErrorMessageHeader is the custom header in which the error message of a failed task is stored.
+const TaskErrorMessageHeader = "errorMessage"
+
+// AdaptiveCreditWaitFraction is the fraction of the remaining lock a task may wait in the client before the adaptive
+// credit policy shrinks the capacity.
+const AdaptiveCreditWaitFraction = 0.25
+
+// creditSmoothing is the weight by which a new sample of the service time is divided when it is averaged.
+const creditSmoothing = 8
+
 const requestQueueSize uint64 = 4096
 
 const stateLeader = "LEADER"
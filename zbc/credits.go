@@ -0,0 +1,346 @@
+package zbc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+)
+
+// CreditObservation describes a task which was just handed to the consumer of a task subscription partition.
+type CreditObservation struct {
+	PartitionID uint16
+
+	// Credits is the number of credits configured for the subscription.
+	Credits int32
+	// Capacity is the number of credits the partition currently holds.
+	Capacity int32
+	// Remaining is the number of tasks the broker can still push before credits are replenished.
+	Remaining int32
+
+	// Wait is how long the task waited in the client before it was taken by the consumer.
+	Wait time.Duration
+	// ServiceTime is the smoothed time the consumer needs to take the next task while tasks are queued. It is zero as
+	// long as the consumer takes tasks as soon as they arrive.
+	ServiceTime time.Duration
+	// LockRemaining is how long the task stays locked to the subscription.
+	LockRemaining time.Duration
+}
+
+// CreditPolicy decides how many credits a partition of a task subscription holds. The client asks the policy after
+// every task which is handed to the consumer and replenishes the partition up to the returned capacity.
+type CreditPolicy interface {
+	// Limit returns the largest capacity the policy assigns to a partition which is opened with credits.
+	Limit(credits int32) int32
+	// Capacity returns the number of credits the partition should hold.
+	Capacity(obs CreditObservation) int32
+}
+
+// FixedCreditPolicy keeps the capacity at the credits configured for the subscription. It is used unless another
+// policy is configured.
+type FixedCreditPolicy struct{}
+
+// Limit returns credits.
+func (FixedCreditPolicy) Limit(credits int32) int32 {
+	return credits
+}
+
+// Capacity returns the configured credits.
+func (FixedCreditPolicy) Capacity(obs CreditObservation) int32 {
+	return obs.Credits
+}
+
+// AdaptiveCreditPolicy sizes the capacity to the speed of the consumer. Capacity grows by one credit while tasks are
+// taken quickly and shrinks when a task waits in the client longer than WaitFraction of its remaining lock. It never
+// exceeds the number of tasks the consumer can take before the lock expires.
+type AdaptiveCreditPolicy struct {
+	Min          int32
+	Max          int32
+	WaitFraction float64
+}
+
+// NewAdaptiveCreditPolicy creates an adaptive policy which keeps the capacity between min and max.
+func NewAdaptiveCreditPolicy(min, max int32) *AdaptiveCreditPolicy {
+	return &AdaptiveCreditPolicy{
+		Min:          min,
+		Max:          max,
+		WaitFraction: AdaptiveCreditWaitFraction,
+	}
+}
+
+// Limit returns the maximum of the policy.
+func (p *AdaptiveCreditPolicy) Limit(credits int32) int32 {
+	if p.Max < credits {
+		return credits
+	}
+	return p.Max
+}
+
+// Capacity grows or shrinks the capacity depending on how long the task waited.
+func (p *AdaptiveCreditPolicy) Capacity(obs CreditObservation) int32 {
+	capacity := obs.Capacity
+	maxWait := time.Duration(float64(obs.LockRemaining) * p.WaitFraction)
+	if obs.Wait <= maxWait {
+		capacity++
+	} else if obs.ServiceTime > 0 {
+		// Shrink to as many tasks as the consumer takes within the allowed wait. Tasks which are queued already
+		// waited as well, so the capacity isn't reduced again for each of them.
+		if fit := int64(maxWait / obs.ServiceTime); fit < int64(capacity) {
+			capacity = int32(fit)
+		}
+	} else {
+		capacity /= 2
+	}
+
+	if obs.ServiceTime > 0 {
+		if fit := int64(obs.LockRemaining / obs.ServiceTime); fit < int64(capacity) {
+			capacity = int32(fit)
+		}
+	}
+
+	if capacity > p.Max {
+		capacity = p.Max
+	}
+	if capacity < p.Min {
+		capacity = p.Min
+	}
+	return capacity
+}
+
+// PartitionCredits is the credit state of one partition of a task subscription.
+type PartitionCredits struct {
+	PartitionID   uint16
+	SubscriberKey uint64
+
+	// Capacity is the number of credits the partition currently holds.
+	Capacity int32
+	// Remaining is the number of tasks the broker can still push before credits are replenished.
+	Remaining int32
+}
+
+// creditConfig holds the credit settings with which a task subscription is opened. Partitions of subscriptions which
+// share a budget never hold more credits together than the budget allows.
+type creditConfig struct {
+	credits   int32
+	threshold int32
+	policy    CreditPolicy
+	budget    *creditBudget
+}
+
+// creditBudget is the number of credits which the partitions of several task subscriptions share.
+type creditBudget struct {
+	mu    sync.Mutex
+	total int32
+	held  int32
+}
+
+// take will account for a partition which is opened with capacity credits. Every partition holds at least one credit,
+// even if the budget is used up.
+func (b *creditBudget) take(capacity int32) {
+	b.mu.Lock()
+	b.held += capacity
+	b.mu.Unlock()
+}
+
+// resize will move a partition from capacity to wanted and return the capacity granted by the budget. Shrinking is
+// always granted, growing only as far as the budget has credits left.
+func (b *creditBudget) resize(capacity, wanted int32) int32 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if wanted > capacity {
+		free := b.total - b.held
+		if free < 0 {
+			free = 0
+		}
+		if wanted-capacity > free {
+			wanted = capacity + free
+		}
+	}
+	b.held += wanted - capacity
+	return wanted
+}
+
+func newCreditBudget(total int32) *creditBudget {
+	return &creditBudget{total: total}
+}
+
+// partitionCredits tracks the credits of one partition of a task subscription. A credit is used up when the task is
+// handed to the consumer, and the used credits are given back once the remaining ones drop below the threshold.
+type partitionCredits struct {
+	mu           sync.Mutex
+	subscription zbmsgpack.TaskSubscription
+	lockDuration time.Duration
+	policy       CreditPolicy
+	budget       *creditBudget
+	credits      int32
+	limit        int32
+	capacity     int32
+	remaining    int32
+	threshold    int32
+
+	lastHandOff time.Time
+	serviceTime time.Duration
+}
+
+// consume will use up one credit for the task which was offered to the consumer at offered and return the number
+// of credits which have to be given back to the broker, together with the subscription they belong to. If limit is
+// positive, the capacity never exceeds it.
+func (pc *partitionCredits) consume(event *SubscriptionEvent, offered time.Time, limit int32) (int32, zbmsgpack.TaskSubscription) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	// Consumer was busy from the moment the task was offered or the previous one was taken, whichever came later.
+	now := time.Now()
+	start := offered
+	if pc.lastHandOff.After(start) {
+		start = pc.lastHandOff
+	}
+	pc.serviceTime += (now.Sub(start) - pc.serviceTime) / creditSmoothing
+	pc.lastHandOff = now
+	pc.remaining--
+
+	capacity := pc.policy.Capacity(CreditObservation{
+		PartitionID:   pc.subscription.PartitionID,
+		Credits:       pc.credits,
+		Capacity:      pc.capacity,
+		Remaining:     pc.remaining,
+		Wait:          now.Sub(event.received),
+		ServiceTime:   pc.serviceTime,
+		LockRemaining: pc.lockRemaining(event.Task, now),
+	})
+	if capacity > pc.limit {
+		capacity = pc.limit
+	}
+	if limit > 0 && capacity > limit {
+		capacity = limit
+	}
+	if capacity < 1 {
+		capacity = 1
+	}
+	if pc.budget != nil {
+		capacity = pc.budget.resize(pc.capacity, capacity)
+	}
+	pc.capacity = capacity
+
+	if pc.remaining >= pc.thresholdLocked() {
+		return 0, pc.subscription
+	}
+
+	credits := pc.capacity - pc.remaining
+	pc.remaining = pc.capacity
+	return credits, pc.subscription
+}
+
+// thresholdLocked returns the threshold for the current capacity. Unless it is set explicitly, credits are
+// replenished when half of them are used up.
+func (pc *partitionCredits) thresholdLocked() int32 {
+	threshold := pc.threshold
+	if threshold == 0 {
+		threshold = pc.capacity / 2
+	}
+	if threshold > pc.capacity {
+		threshold = pc.capacity
+	}
+	if threshold < 1 {
+		threshold = 1
+	}
+	return threshold
+}
+
+func (pc *partitionCredits) lockRemaining(task *zbmsgpack.Task, now time.Time) time.Duration {
+	lockTime, ok := taskLockTime(task)
+	if !ok {
+		return pc.lockDuration
+	}
+	if remaining := lockTime.Sub(now); remaining < pc.lockDuration {
+		return remaining
+	}
+	return pc.lockDuration
+}
+
+// resubscribed will switch over to the reopened subscription, which holds the full capacity.
+func (pc *partitionCredits) resubscribed(subscription zbmsgpack.TaskSubscription) {
+	pc.mu.Lock()
+	pc.subscription = subscription
+	pc.remaining = pc.capacity
+	pc.mu.Unlock()
+}
+
+// taskSubscription returns the subscription the credits belong to.
+func (pc *partitionCredits) taskSubscription() zbmsgpack.TaskSubscription {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.subscription
+}
+
+// restore will take back credits which couldn't be given to the broker.
+func (pc *partitionCredits) restore(credits int32) {
+	pc.mu.Lock()
+	pc.remaining -= credits
+	pc.mu.Unlock()
+}
+
+func (pc *partitionCredits) state() PartitionCredits {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	return PartitionCredits{
+		PartitionID:   pc.subscription.PartitionID,
+		SubscriberKey: pc.subscription.SubscriberKey,
+		Capacity:      pc.capacity,
+		Remaining:     pc.remaining,
+	}
+}
+
+// newPartitionCredits creates the credits of a partition which was opened with subscription.Credits.
+func newPartitionCredits(subscription zbmsgpack.TaskSubscription, lockDuration time.Duration, config creditConfig) *partitionCredits {
+	if config.budget != nil {
+		config.budget.take(subscription.Credits)
+	}
+	return &partitionCredits{
+		subscription: subscription,
+		lockDuration: lockDuration,
+		policy:       config.policy,
+		budget:       config.budget,
+		credits:      config.credits,
+		limit:        config.policy.Limit(config.credits),
+		capacity:     subscription.Credits,
+		remaining:    subscription.Credits,
+		threshold:    config.threshold,
+	}
+}
+
+// replenishCredits will use up a credit of the partition and give the used credits back to the broker when needed.
+func (rm *requestManager) replenishCredits(pc *partitionCredits, event *SubscriptionEvent, offered time.Time, limit int32) {
+	credits, sub := pc.consume(event, offered, limit)
+	if credits <= 0 {
+		return
+	}
+
+	// Increase request is sent in the background so that delivery of the tasks isn't held up.
+	sub.Credits = credits
+	go func() {
+		if _, err := rm.increaseTaskSubscriptionCredits(context.Background(), &sub); err != nil {
+			pc.restore(credits)
+		}
+	}()
+}
+
+// creditState returns the credit state of every partition of the task subscription.
+func (rm *requestManager) creditState(sub *zbmsgpack.TaskSubscriptionInfo) []PartitionCredits {
+	rm.subscriptionsMu.Lock()
+	defer rm.subscriptionsMu.Unlock()
+
+	entry, ok := rm.taskSubscriptions[sub]
+	if !ok {
+		return nil
+	}
+
+	states := make([]PartitionCredits, 0, len(entry.credits))
+	for _, pc := range entry.credits {
+		states = append(states, pc.state())
+	}
+	return states
+}
@@ -0,0 +1,178 @@
+package zbc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+)
+
+func TestFixedCreditPolicy(t *testing.T) {
+	policy := FixedCreditPolicy{}
+	if limit := policy.Limit(8); limit != 8 {
+		t.Fatalf("expected limit 8, got %d", limit)
+	}
+	// Capacity which was clamped, e.g. to the burst of a rate limit, returns to the configured credits.
+	if capacity := policy.Capacity(CreditObservation{Credits: 8, Capacity: 3}); capacity != 8 {
+		t.Fatalf("expected capacity 8, got %d", capacity)
+	}
+}
+
+func TestAdaptiveCreditPolicy(t *testing.T) {
+	policy := NewAdaptiveCreditPolicy(2, 20)
+	lock := 40 * time.Second // allows a wait of 10s
+
+	tests := []struct {
+		name     string
+		obs      CreditObservation
+		capacity int32
+	}{
+		{"grows when tasks are taken quickly", CreditObservation{Capacity: 10, Wait: time.Second, LockRemaining: lock}, 11},
+		{"grows up to max", CreditObservation{Capacity: 20, Wait: time.Second, LockRemaining: lock}, 20},
+		{"shrinks to what fits into the wait", CreditObservation{Capacity: 10, Wait: 12 * time.Second, ServiceTime: 2 * time.Second, LockRemaining: lock}, 5},
+		{"doesn't grow when shrinking would", CreditObservation{Capacity: 4, Wait: 12 * time.Second, ServiceTime: 2 * time.Second, LockRemaining: lock}, 4},
+		{"halves without service time", CreditObservation{Capacity: 10, Wait: 12 * time.Second, LockRemaining: lock}, 5},
+		{"fits into the lock", CreditObservation{Capacity: 10, Wait: time.Second, ServiceTime: 8 * time.Second, LockRemaining: lock}, 5},
+		{"never below min", CreditObservation{Capacity: 10, Wait: 12 * time.Second, ServiceTime: 30 * time.Second, LockRemaining: lock}, 2},
+	}
+
+	for _, test := range tests {
+		if capacity := policy.Capacity(test.obs); capacity != test.capacity {
+			t.Errorf("%s: expected capacity %d, got %d", test.name, test.capacity, capacity)
+		}
+	}
+
+	if limit := policy.Limit(8); limit != 20 {
+		t.Fatalf("expected limit 20, got %d", limit)
+	}
+	if limit := policy.Limit(32); limit != 32 {
+		t.Fatalf("expected limit 32, got %d", limit)
+	}
+}
+
+// capacityPolicy always asks for the same capacity.
+type capacityPolicy int32
+
+func (p capacityPolicy) Limit(credits int32) int32 {
+	return int32(p)
+}
+
+func (p capacityPolicy) Capacity(obs CreditObservation) int32 {
+	return int32(p)
+}
+
+func newTestPartitionCredits(opened int32, config creditConfig) *partitionCredits {
+	subscription := zbmsgpack.TaskSubscription{PartitionID: 1, SubscriberKey: 2, Credits: opened}
+	return newPartitionCredits(subscription, time.Minute, config)
+}
+
+// consumeAll consumes n tasks with the given limit and returns the credits which were given back after each of them.
+func consumeAll(pc *partitionCredits, n int, limit int32) []int32 {
+	replenished := make([]int32, n)
+	for i := range replenished {
+		event := &SubscriptionEvent{Task: &zbmsgpack.Task{}, received: time.Now()}
+		replenished[i], _ = pc.consume(event, time.Now(), limit)
+	}
+	return replenished
+}
+
+func TestPartitionCreditsConsume(t *testing.T) {
+	tests := []struct {
+		name        string
+		opened      int32
+		config      creditConfig
+		limit       int32
+		replenished []int32
+		capacity    int32
+	}{
+		{
+			name:        "replenishes once half is used up",
+			opened:      4,
+			config:      creditConfig{credits: 4, policy: FixedCreditPolicy{}},
+			replenished: []int32{0, 0, 3, 0, 0, 3},
+			capacity:    4,
+		},
+		{
+			name:        "explicit threshold",
+			opened:      4,
+			config:      creditConfig{credits: 4, threshold: 1, policy: FixedCreditPolicy{}},
+			replenished: []int32{0, 0, 0, 4, 0, 0, 0, 4},
+			capacity:    4,
+		},
+		{
+			name:        "threshold above capacity",
+			opened:      2,
+			config:      creditConfig{credits: 2, threshold: 5, policy: FixedCreditPolicy{}},
+			replenished: []int32{1, 1, 1},
+			capacity:    2,
+		},
+		{
+			name:        "capacity is clamped to the limit of the policy",
+			opened:      4,
+			config:      creditConfig{credits: 4, policy: &AdaptiveCreditPolicy{Min: 1, Max: 6, WaitFraction: 1}},
+			replenished: []int32{0, 4, 0, 0, 0, 4},
+			capacity:    6,
+		},
+		{
+			name:        "capacity is clamped to the burst",
+			opened:      4,
+			config:      creditConfig{credits: 8, policy: FixedCreditPolicy{}},
+			limit:       3,
+			replenished: []int32{0, 0, 0, 3, 0, 0, 3},
+			capacity:    3,
+		},
+		{
+			name:        "capacity is at least one",
+			opened:      1,
+			config:      creditConfig{credits: 1, policy: capacityPolicy(0)},
+			replenished: []int32{1, 1},
+			capacity:    1,
+		},
+	}
+
+	for _, test := range tests {
+		pc := newTestPartitionCredits(test.opened, test.config)
+		replenished := consumeAll(pc, len(test.replenished), test.limit)
+		for i := range replenished {
+			if replenished[i] != test.replenished[i] {
+				t.Errorf("%s: expected replenished %v, got %v", test.name, test.replenished, replenished)
+				break
+			}
+		}
+
+		state := pc.state()
+		if state.Capacity != test.capacity {
+			t.Errorf("%s: expected capacity %d, got %d", test.name, test.capacity, state.Capacity)
+		}
+		if state.Remaining < 1 || state.Remaining > state.Capacity {
+			t.Errorf("%s: remaining %d out of range", test.name, state.Remaining)
+		}
+	}
+}
+
+func TestPartitionCreditsRestore(t *testing.T) {
+	pc := newTestPartitionCredits(4, creditConfig{credits: 4, policy: FixedCreditPolicy{}})
+
+	if replenished := consumeAll(pc, 3, 0); replenished[2] != 3 {
+		t.Fatalf("expected 3 credits to be replenished, got %v", replenished)
+	}
+
+	// Increase request failed, so the broker still holds one credit only.
+	pc.restore(3)
+	if state := pc.state(); state.Remaining != 1 {
+		t.Fatalf("expected 1 remaining credit, got %d", state.Remaining)
+	}
+
+	credits, sub := pc.consume(&SubscriptionEvent{Task: &zbmsgpack.Task{}, received: time.Now()}, time.Now(), 0)
+	if credits != 4 || sub.SubscriberKey != 2 {
+		t.Fatalf("expected all 4 credits of subscriber 2 to be replenished, got %d of %d", credits, sub.SubscriberKey)
+	}
+
+	pc.resubscribed(zbmsgpack.TaskSubscription{PartitionID: 1, SubscriberKey: 3, Credits: 4})
+	if state := pc.state(); state.Remaining != 4 || state.SubscriberKey != 3 {
+		t.Fatalf("unexpected state after resubscribe %+v", state)
+	}
+	if sub := pc.taskSubscription(); sub.SubscriberKey != 3 {
+		t.Fatalf("expected subscription 3, got %d", sub.SubscriberKey)
+	}
+}
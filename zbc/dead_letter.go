@@ -0,0 +1,193 @@
+package zbc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+	"github.com/zeebe-io/zbc-go/zbc/zbsbe"
+)
+
+// DeadLetter is a task which was failed with no retries left, so that the broker raised an incident for it.
+type DeadLetter struct {
+	Task *zbmsgpack.Task `json:"task"`
+
+	Key           uint64 `json:"key"`
+	PartitionID   uint16 `json:"partitionId"`
+	Position      uint64 `json:"position"`
+	SubscriberKey uint64 `json:"subscriberKey"`
+
+	Error string    `json:"error,omitempty"`
+	Stack string    `json:"stack,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// event returns the subscription event through which commands on the dead-lettered task can be sent.
+func (dl *DeadLetter) event() *SubscriptionEvent {
+	return &SubscriptionEvent{
+		Task:  dl.Task,
+		Value: dl.Task,
+		Event: &zbsbe.SubscribedEvent{
+			PartitionId:      dl.PartitionID,
+			Position:         dl.Position,
+			Key:              dl.Key,
+			SubscriberKey:    dl.SubscriberKey,
+			SubscriptionType: zbsbe.SubscriptionType.TASK_SUBSCRIPTION,
+			EventType:        zbsbe.EventType.TASK_EVENT,
+		},
+	}
+}
+
+// newDeadLetter records the task failed by the event, with the retries it is left with.
+func newDeadLetter(task *SubscriptionEvent, cause error) *DeadLetter {
+	failed := taskCommand(task, TaskFailed)
+	failed.Retries--
+
+	dl := &DeadLetter{
+		Task:          failed,
+		Key:           task.Event.Key,
+		PartitionID:   task.Event.PartitionId,
+		Position:      task.Event.Position,
+		SubscriberKey: task.Event.SubscriberKey,
+		Time:          time.Now(),
+	}
+	if cause != nil {
+		dl.Error = cause.Error()
+	}
+	if panicErr, ok := cause.(*PanicError); ok {
+		dl.Stack = string(panicErr.Stack)
+	}
+	return dl
+}
+
+// DeadLetterSink stores dead-lettered tasks, see WithDeadLetterSink.
+type DeadLetterSink interface {
+	// Store records the dead-lettered task.
+	Store(letter *DeadLetter) error
+	// List returns all the dead-lettered tasks in the order they were stored.
+	List() ([]*DeadLetter, error)
+	// Remove forgets the dead-lettered task with the given key on the given partition. Keys are unique within a
+	// partition only.
+	Remove(partitionID uint16, key uint64) error
+}
+
+// FileDeadLetterSink stores dead-lettered tasks in a file, one JSON document per line.
+type FileDeadLetterSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileDeadLetterSink creates a sink which stores dead-lettered tasks in the file at path. The file is created on
+// the first store.
+func NewFileDeadLetterSink(path string) *FileDeadLetterSink {
+	return &FileDeadLetterSink{path: path}
+}
+
+// Store appends the dead-lettered task to the file.
+func (s *FileDeadLetterSink) Store(letter *DeadLetter) error {
+	b, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// List reads all the dead-lettered tasks from the file. A missing file holds no tasks.
+func (s *FileDeadLetterSink) List() ([]*DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+// Remove rewrites the file without the dead-lettered task with the given key on the given partition.
+func (s *FileDeadLetterSink) Remove(partitionID uint16, key uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	letters, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	var kept []byte
+	for _, letter := range letters {
+		if letter.PartitionID == partitionID && letter.Key == key {
+			continue
+		}
+		b, err := json.Marshal(letter)
+		if err != nil {
+			return err
+		}
+		kept = append(append(kept, b...), '\n')
+	}
+
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, kept, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *FileDeadLetterSink) read() ([]*DeadLetter, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var letters []*DeadLetter
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), DeadLetterMaxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		letter := &DeadLetter{}
+		if err := json.Unmarshal(scanner.Bytes(), letter); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", s.path, line, err)
+		}
+		letters = append(letters, letter)
+	}
+	return letters, scanner.Err()
+}
+
+// deadLetter will store the failed task in the dead-letter sink if failing it used up its last retry.
+func (rm *requestManager) deadLetter(task *SubscriptionEvent, cause error) error {
+	if rm.config.deadLetterSink == nil || task.Task.Retries > 1 {
+		return nil
+	}
+	if err := rm.config.deadLetterSink.Store(newDeadLetter(task, cause)); err != nil {
+		return fmt.Errorf("task failed but storing the dead letter failed: %v", err)
+	}
+	return nil
+}
+
+// replayDeadLetter will reset the retries of the dead-lettered task and remove it from the sink.
+func (rm *requestManager) replayDeadLetter(ctx context.Context, sink DeadLetterSink, letter *DeadLetter, retries int) (*zbmsgpack.Task, error) {
+	task, err := rm.updateTaskRetries(ctx, letter.event(), retries)
+	if err != nil {
+		return task, err
+	}
+	return task, sink.Remove(letter.PartitionID, letter.Key)
+}
@@ -0,0 +1,145 @@
+package zbc
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestSink(t *testing.T) (*FileDeadLetterSink, func()) {
+	dir, err := ioutil.TempDir("", "zbc-dead-letter")
+	if err != nil {
+		t.Fatalf("creating temp dir failed: %s", err)
+	}
+	return NewFileDeadLetterSink(filepath.Join(dir, "dead-letters.ndjson")), func() { os.RemoveAll(dir) }
+}
+
+func deadLetterWithKey(partitionID uint16, key uint64) *DeadLetter {
+	event := newTaskEvent(1)
+	event.Event.PartitionId = partitionID
+	event.Event.Key = key
+	return newDeadLetter(event, errors.New("kaputt"))
+}
+
+func TestFileDeadLetterSink(t *testing.T) {
+	sink, cleanup := newTestSink(t)
+	defer cleanup()
+
+	letters, err := sink.List()
+	if err != nil || len(letters) != 0 {
+		t.Fatalf("expected missing file to hold no letters, got %v, %v", letters, err)
+	}
+	if err := sink.Remove(1, 1); err != nil {
+		t.Fatalf("Remove on missing file failed: %s", err)
+	}
+
+	for _, key := range []uint64{1, 2, 3} {
+		if err := sink.Store(deadLetterWithKey(1, key)); err != nil {
+			t.Fatalf("Store failed: %s", err)
+		}
+	}
+
+	letters, err = sink.List()
+	if err != nil || len(letters) != 3 {
+		t.Fatalf("expected 3 letters, got %v, %v", letters, err)
+	}
+	if letters[0].Key != 1 || letters[0].Task.Type != "foo" || letters[0].Error != "kaputt" || letters[0].PartitionID != 1 {
+		t.Fatalf("unexpected letter %+v", letters[0])
+	}
+
+	if err := sink.Remove(1, 2); err != nil {
+		t.Fatalf("Remove failed: %s", err)
+	}
+	letters, err = sink.List()
+	if err != nil || len(letters) != 2 || letters[0].Key != 1 || letters[1].Key != 3 {
+		t.Fatalf("unexpected letters after remove %v, %v", letters, err)
+	}
+	if _, err := os.Stat(sink.path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file left behind: %v", err)
+	}
+}
+
+func TestFileDeadLetterSinkSameKeyOnTwoPartitions(t *testing.T) {
+	sink, cleanup := newTestSink(t)
+	defer cleanup()
+
+	// Keys are unique within a partition only, so both tasks are kept apart.
+	for _, partitionID := range []uint16{1, 2} {
+		if err := sink.Store(deadLetterWithKey(partitionID, 7)); err != nil {
+			t.Fatalf("Store failed: %s", err)
+		}
+	}
+
+	if err := sink.Remove(2, 7); err != nil {
+		t.Fatalf("Remove failed: %s", err)
+	}
+	letters, err := sink.List()
+	if err != nil || len(letters) != 1 || letters[0].PartitionID != 1 || letters[0].Key != 7 {
+		t.Fatalf("expected the letter of partition 1 to be kept, got %v, %v", letters, err)
+	}
+}
+
+func TestFileDeadLetterSinkMalformedLine(t *testing.T) {
+	sink, cleanup := newTestSink(t)
+	defer cleanup()
+
+	sink.Store(deadLetterWithKey(1, 1))
+	f, err := os.OpenFile(sink.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("opening sink failed: %s", err)
+	}
+	f.WriteString("\n{not json\n")
+	f.Close()
+
+	if _, err := sink.List(); err == nil || !strings.Contains(err.Error(), ":3:") {
+		t.Fatalf("expected error for line 3, got %v", err)
+	}
+	if err := sink.Remove(1, 1); err == nil {
+		t.Fatal("expected Remove to refuse rewriting a malformed file")
+	}
+}
+
+func TestDeadLetterOnLastRetry(t *testing.T) {
+	sink, cleanup := newTestSink(t)
+	defer cleanup()
+	rm := &requestManager{topologyManager: &topologyManager{transportManager: &transportManager{config: &clientConfig{deadLetterSink: sink}}}}
+
+	// Failing a task with retries left doesn't dead-letter it.
+	if err := rm.deadLetter(newTaskEvent(2), errors.New("kaputt")); err != nil {
+		t.Fatalf("deadLetter failed: %s", err)
+	}
+	if letters, _ := sink.List(); len(letters) != 0 {
+		t.Fatalf("expected no letters, got %d", len(letters))
+	}
+
+	event := newTaskEvent(1)
+	cause := &PanicError{Value: "boom", Stack: []byte("stack")}
+	if err := rm.deadLetter(event, cause); err != nil {
+		t.Fatalf("deadLetter failed: %s", err)
+	}
+
+	letters, err := sink.List()
+	if err != nil || len(letters) != 1 {
+		t.Fatalf("expected one letter, got %v, %v", letters, err)
+	}
+	letter := letters[0]
+	if letter.Task.Retries != 0 || letter.Task.State != TaskFailed || letter.Error != cause.Error() || letter.Stack != "stack" {
+		t.Fatalf("unexpected letter %+v with task %+v", letter, letter.Task)
+	}
+	if event.Task.Retries != 1 || event.Task.State != TaskCreated {
+		t.Fatalf("task of the event changed to %+v", event.Task)
+	}
+
+	replay := letter.event()
+	if replay.Event.Key != event.Event.Key || replay.Event.PartitionId != event.Event.PartitionId || replay.Event.Position != event.Event.Position {
+		t.Fatalf("replay event doesn't address the task %+v", replay.Event)
+	}
+
+	rm.config.deadLetterSink = nil
+	if err := rm.deadLetter(newTaskEvent(0), nil); err != nil {
+		t.Fatalf("deadLetter without sink failed: %s", err)
+	}
+}
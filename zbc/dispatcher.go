@@ -1,30 +1,88 @@
 package zbc
 
 import (
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
 	"github.com/zeebe-io/zbc-go/zbc/zbsbe"
 )
 
 type dispatcher struct {
+	transactionsMu      sync.Mutex
 	lastTransactionSeed uint64
 	activeTransactions  []*requestWrapper
 
 	lastSubscriptionSeed uint64
 	subscriptions        *subscriptionsManager
+
+	// Shared by all the sockets of the client, so the count survives reconnects.
+	unknownSubscriberEvents *uint64
+
+	closeCh <-chan struct{}
 }
 
+// addTransaction will assign the request to the next free slot and set its RequestID accordingly. The number of requests
+// in flight is bounded by requestQueueSize, so a free slot is always found.
 func (d *dispatcher) addTransaction(request *requestWrapper) {
-	d.lastTransactionSeed += requestQueueSize + 1
-	request.payload.Headers.RequestResponseHeader.RequestID = d.lastTransactionSeed
+	d.transactionsMu.Lock()
+	defer d.transactionsMu.Unlock()
+
+	var index uint64
+	for i := uint64(0); i < requestQueueSize; i++ {
+		d.lastTransactionSeed += requestQueueSize + 1
+		index = d.lastTransactionSeed & (requestQueueSize - 1)
+		if d.activeTransactions[index] == nil {
+			break
+		}
+	}
 
-	index := d.lastTransactionSeed & (requestQueueSize - 1)
+	request.payload.Headers.RequestResponseHeader.RequestID = d.lastTransactionSeed
 	d.activeTransactions[index] = request
 }
 
+func (d *dispatcher) removeTransaction(request *requestWrapper) {
+	d.transactionsMu.Lock()
+	defer d.transactionsMu.Unlock()
+
+	index := request.payload.Headers.RequestResponseHeader.RequestID & (requestQueueSize - 1)
+	if d.activeTransactions[index] == request {
+		d.activeTransactions[index] = nil
+	}
+}
+
+// failTransactions will fail all the requests which are waiting for the response with the given error.
+func (d *dispatcher) failTransactions(err error) {
+	d.transactionsMu.Lock()
+	defer d.transactionsMu.Unlock()
+
+	for index, request := range d.activeTransactions {
+		if request == nil {
+			continue
+		}
+		d.activeTransactions[index] = nil
+		request.fail(err)
+	}
+}
+
 func (d *dispatcher) dispatchTransaction(transactionID uint64, response *Message) {
+	d.transactionsMu.Lock()
 	index := transactionID & (requestQueueSize - 1)
 	request := d.activeTransactions[index]
-	request.responseCh <- response
+
+	// Request was cancelled by the caller in the meantime, its slot may already be held by another request.
+	if request == nil || request.payload.Headers.RequestResponseHeader.RequestID != transactionID {
+		d.transactionsMu.Unlock()
+		return
+	}
+	d.activeTransactions[index] = nil
+	d.transactionsMu.Unlock()
+
+	select {
+	case request.responseCh <- response:
+	default:
+	}
 }
 
 func (d *dispatcher) addTaskSubscription(key uint64, value interface{}) {
@@ -36,19 +94,29 @@ func (d *dispatcher) addTopicSubscription(key uint64, value interface{}) {
 }
 
 func (d *dispatcher) dispatchTaskEvent(key uint64, message *zbsbe.SubscribedEvent, task *zbmsgpack.Task) {
-	if ch := d.subscriptions.getTaskChannel(key); ch != nil {
-		ch <- &SubscriptionEvent{
-			Task: task, Event: message,
-		}
+	ch := d.subscriptions.getTaskChannel(key)
+	if ch == nil {
+		atomic.AddUint64(d.unknownSubscriberEvents, 1)
+		return
+	}
+
+	select {
+	case ch <- &SubscriptionEvent{Task: task, Value: task, Event: message, received: time.Now()}:
+	case <-d.closeCh:
 	}
 }
 
-func (d *dispatcher) dispatchTopicEvent(key uint64, message *zbsbe.SubscribedEvent) {
-	if ch := d.subscriptions.getTopicChannel(key); ch != nil {
-		ch <- &SubscriptionEvent{
-			Task:  nil,
-			Event: message,
-		}
+func (d *dispatcher) dispatchTopicEvent(key uint64, message *zbsbe.SubscribedEvent, value interface{}) {
+	ch := d.subscriptions.getTopicChannel(key)
+	if ch == nil {
+		atomic.AddUint64(d.unknownSubscriberEvents, 1)
+		return
+	}
+
+	task, _ := value.(*zbmsgpack.Task)
+	select {
+	case ch <- &SubscriptionEvent{Task: task, Value: value, Event: message, received: time.Now()}:
+	case <-d.closeCh:
 	}
 }
 
@@ -60,11 +128,14 @@ func (d *dispatcher) removeTopicSubscription(key uint64) {
 	d.subscriptions.removeTopicSubscription(key)
 }
 
-func newDispatcher() dispatcher {
-	return dispatcher{
-		0,
-		make([]*requestWrapper, requestQueueSize),
-		0,
-		newSubscriptionsManager(),
+func newDispatcher(closeCh <-chan struct{}, unknownSubscriberEvents *uint64) *dispatcher {
+	return &dispatcher{
+		lastTransactionSeed:  0,
+		activeTransactions:   make([]*requestWrapper, requestQueueSize),
+		lastSubscriptionSeed: 0,
+		subscriptions:        newSubscriptionsManager(),
+		closeCh:              closeCh,
+
+		unknownSubscriberEvents: unknownSubscriberEvents,
 	}
 }
@@ -0,0 +1,93 @@
+package zbc
+
+import (
+	"testing"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbprotocol"
+)
+
+func newTestTransaction() *requestWrapper {
+	return newRequestWrapper(&Message{Headers: &Headers{RequestResponseHeader: &zbprotocol.RequestResponseHeader{}}})
+}
+
+func transactionID(request *requestWrapper) uint64 {
+	return request.payload.Headers.RequestResponseHeader.RequestID
+}
+
+func TestDispatchTransaction(t *testing.T) {
+	d := newDispatcher(make(chan struct{}), new(uint64))
+	request := newTestTransaction()
+	d.addTransaction(request)
+
+	response := &Message{}
+	d.dispatchTransaction(transactionID(request), response)
+	select {
+	case got := <-request.responseCh:
+		if got != response {
+			t.Fatal("unexpected response")
+		}
+	default:
+		t.Fatal("response wasn't dispatched")
+	}
+
+	// Duplicate response finds the slot empty.
+	d.dispatchTransaction(transactionID(request), response)
+	if len(request.responseCh) != 0 {
+		t.Fatal("duplicate response was dispatched")
+	}
+}
+
+func TestDispatchTransactionReusedSlot(t *testing.T) {
+	d := newDispatcher(make(chan struct{}), new(uint64))
+
+	timedOut := newTestTransaction()
+	d.addTransaction(timedOut)
+	d.removeTransaction(timedOut)
+
+	// Go around all the slots once, so the next request takes the slot of the one which timed out.
+	for i := uint64(0); i < requestQueueSize-1; i++ {
+		request := newTestTransaction()
+		d.addTransaction(request)
+		d.removeTransaction(request)
+	}
+	request := newTestTransaction()
+	d.addTransaction(request)
+
+	index := transactionID(request) & (requestQueueSize - 1)
+	if index != transactionID(timedOut)&(requestQueueSize-1) || transactionID(request) == transactionID(timedOut) {
+		t.Fatalf("expected slot %d to be reused with a new id", index)
+	}
+
+	// Late response of the request which timed out must not reach the request which holds its slot now.
+	d.dispatchTransaction(transactionID(timedOut), &Message{})
+	if len(request.responseCh) != 0 {
+		t.Fatal("late response was dispatched to the request which reused the slot")
+	}
+	if d.activeTransactions[index] != request {
+		t.Fatal("late response freed the slot")
+	}
+
+	response := &Message{}
+	d.dispatchTransaction(transactionID(request), response)
+	if got := <-request.responseCh; got != response {
+		t.Fatal("unexpected response")
+	}
+}
+
+func TestFailTransactions(t *testing.T) {
+	d := newDispatcher(make(chan struct{}), new(uint64))
+	requests := []*requestWrapper{newTestTransaction(), newTestTransaction()}
+	for _, request := range requests {
+		d.addTransaction(request)
+	}
+
+	d.failTransactions(errTimeout)
+	for _, request := range requests {
+		if err := <-request.errorCh; err != errTimeout {
+			t.Fatalf("expected errTimeout, got %v", err)
+		}
+		if d.activeTransactions[transactionID(request)&(requestQueueSize-1)] != nil {
+			t.Fatal("slot wasn't freed")
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package zbc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbsbe"
+)
+
+var errorCodeNames = map[zbsbe.ErrorCodeEnum]string{
+	zbsbe.ErrorCode.MESSAGE_NOT_SUPPORTED:      "MESSAGE_NOT_SUPPORTED",
+	zbsbe.ErrorCode.PARTITION_NOT_FOUND:        "PARTITION_NOT_FOUND",
+	zbsbe.ErrorCode.REQUEST_WRITE_FAILURE:      "REQUEST_WRITE_FAILURE",
+	zbsbe.ErrorCode.INVALID_CLIENT_VERSION:     "INVALID_CLIENT_VERSION",
+	zbsbe.ErrorCode.REQUEST_TIMEOUT:            "REQUEST_TIMEOUT",
+	zbsbe.ErrorCode.REQUEST_PROCESSING_FAILURE: "REQUEST_PROCESSING_FAILURE",
+}
+
+// BrokerError is returned when the broker answers a request with an error response.
+type BrokerError struct {
+	Code          zbsbe.ErrorCodeEnum
+	Message       string
+	FailedRequest []byte
+}
+
+func (e *BrokerError) Error() string {
+	name, ok := errorCodeNames[e.Code]
+	if !ok {
+		name = fmt.Sprintf("UNKNOWN(%d)", e.Code)
+	}
+	if len(e.Message) == 0 {
+		return fmt.Sprintf("broker error %s", name)
+	}
+	return fmt.Sprintf("broker error %s: %s", name, e.Message)
+}
+
+func newBrokerError(resp *zbsbe.ErrorResponse) *BrokerError {
+	return &BrokerError{
+		Code:          resp.ErrorCode,
+		Message:       string(resp.ErrorData),
+		FailedRequest: resp.FailedRequest,
+	}
+}
+
+func isBrokerErrorCode(err error, code zbsbe.ErrorCodeEnum) bool {
+	brokerErr, ok := err.(*BrokerError)
+	return ok && brokerErr.Code == code
+}
+
+// IsMessageNotSupported reports whether err is a BrokerError with code MESSAGE_NOT_SUPPORTED.
+func IsMessageNotSupported(err error) bool {
+	return isBrokerErrorCode(err, zbsbe.ErrorCode.MESSAGE_NOT_SUPPORTED)
+}
+
+// IsPartitionNotFound reports whether err is a BrokerError with code PARTITION_NOT_FOUND.
+func IsPartitionNotFound(err error) bool {
+	return isBrokerErrorCode(err, zbsbe.ErrorCode.PARTITION_NOT_FOUND)
+}
+
+// IsRequestWriteFailure reports whether err is a BrokerError with code REQUEST_WRITE_FAILURE.
+func IsRequestWriteFailure(err error) bool {
+	return isBrokerErrorCode(err, zbsbe.ErrorCode.REQUEST_WRITE_FAILURE)
+}
+
+// IsInvalidClientVersion reports whether err is a BrokerError with code INVALID_CLIENT_VERSION.
+func IsInvalidClientVersion(err error) bool {
+	return isBrokerErrorCode(err, zbsbe.ErrorCode.INVALID_CLIENT_VERSION)
+}
+
+// IsRequestTimeout reports whether err is a BrokerError with code REQUEST_TIMEOUT.
+func IsRequestTimeout(err error) bool {
+	return isBrokerErrorCode(err, zbsbe.ErrorCode.REQUEST_TIMEOUT)
+}
+
+// IsRequestProcessingFailure reports whether err is a BrokerError with code REQUEST_PROCESSING_FAILURE.
+func IsRequestProcessingFailure(err error) bool {
+	return isBrokerErrorCode(err, zbsbe.ErrorCode.REQUEST_PROCESSING_FAILURE)
+}
+
+// RejectionError is returned when the broker rejected a command. Event holds the decoded rejected event, which is
+// also returned by the method alongside the error.
+type RejectionError struct {
+	Command string
+	State   string
+	Event   interface{}
+}
+
+func (e *RejectionError) Error() string {
+	return fmt.Sprintf("command %s was rejected with state %s", e.Command, e.State)
+}
+
+// IsRejection reports whether err is a RejectionError.
+func IsRejection(err error) bool {
+	_, ok := err.(*RejectionError)
+	return ok
+}
+
+func isRejectedState(state string) bool {
+	return strings.HasSuffix(state, rejectedStateSuffix)
+}
+
+// checkRejection will return RejectionError if the state of the response event says the command was rejected.
+func checkRejection(command, state string, event interface{}) error {
+	if isRejectedState(state) {
+		return &RejectionError{Command: command, State: state, Event: event}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package zbc
+
+import (
+	"context"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+)
+
+// requestFuture holds the result of a request which is executed in the background.
+type requestFuture struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func (f *requestFuture) wait(ctx context.Context) (interface{}, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// newRequestFuture will run op in its own goroutine and resolve the future with its result.
+func newRequestFuture(op func() (interface{}, error)) *requestFuture {
+	f := &requestFuture{done: make(chan struct{})}
+	go func() {
+		f.value, f.err = op()
+		close(f.done)
+	}()
+	return f
+}
+
+// TaskFuture is the pending result of an asynchronous task command.
+type TaskFuture struct {
+	future *requestFuture
+}
+
+// Done returns a channel which is closed once the response or an error is available.
+func (f *TaskFuture) Done() <-chan struct{} {
+	return f.future.done
+}
+
+// Wait blocks until the response is available or ctx is done. Cancelling ctx doesn't cancel the request itself.
+func (f *TaskFuture) Wait(ctx context.Context) (*zbmsgpack.Task, error) {
+	value, err := f.future.wait(ctx)
+	task, _ := value.(*zbmsgpack.Task)
+	return task, err
+}
+
+// WorkflowInstanceFuture is the pending result of an asynchronous workflow instance command.
+type WorkflowInstanceFuture struct {
+	future *requestFuture
+}
+
+// Done returns a channel which is closed once the response or an error is available.
+func (f *WorkflowInstanceFuture) Done() <-chan struct{} {
+	return f.future.done
+}
+
+// Wait blocks until the response is available or ctx is done. Cancelling ctx doesn't cancel the request itself.
+func (f *WorkflowInstanceFuture) Wait(ctx context.Context) (*zbmsgpack.WorkflowInstance, error) {
+	value, err := f.future.wait(ctx)
+	instance, _ := value.(*zbmsgpack.WorkflowInstance)
+	return instance, err
+}
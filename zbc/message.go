@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
 	"github.com/zeebe-io/zbc-go/zbc/zbprotocol"
@@ -93,6 +94,17 @@ func (m *Message) isTopologyMessage() bool {
 	return false
 }
 
+// brokerError will return the error which broker sent instead of the response, or nil if message is not an error response.
+func (m *Message) brokerError() *BrokerError {
+	if m.SbeMessage == nil {
+		return nil
+	}
+	if resp, ok := (*m.SbeMessage).(*zbsbe.ErrorResponse); ok {
+		return newBrokerError(resp)
+	}
+	return nil
+}
+
 func (m *Message) jsonString(data interface{}) string {
 	b, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
@@ -101,13 +113,122 @@ func (m *Message) jsonString(data interface{}) string {
 	return fmt.Sprintf("%+v", string(b))
 }
 
-// SubscriptionEvent is used on task and topic subscription.
+// SubscriptionEvent is used on task and topic subscription. Value holds the event decoded according to its EventType,
+// use a type switch or one of the accessors to read it. It is nil if the event type is unknown.
 type SubscriptionEvent struct {
 	Task  *zbmsgpack.Task
+	Value interface{}
 	Event *zbsbe.SubscribedEvent
+
+	received time.Time
+	deadline time.Time
+	acker    *topicAcker
+}
+
+// Ack marks the event of a topic subscription as processed, it is acknowledged to the broker in the background. It
+// has to be called when the subscription was opened WithManualAck, otherwise events are acknowledged automatically.
+func (se *SubscriptionEvent) Ack() {
+	if se.acker != nil {
+		se.acker.ack(se)
+	}
+}
+
+// Deadline returns the time after which the task should no longer be worked on. It is the lock time of the task
+// less the lock safety margin of the client. ok is false if the event wasn't delivered by a task subscription.
+func (se *SubscriptionEvent) Deadline() (deadline time.Time, ok bool) {
+	return se.deadline, !se.deadline.IsZero()
+}
+
+// LockExpired returns true if the deadline of the task passed.
+func (se *SubscriptionEvent) LockExpired() bool {
+	return !se.deadline.IsZero() && !time.Now().Before(se.deadline)
+}
+
+// setDeadline will set the deadline of the task to its lock time less margin. Events without lock time keep no deadline.
+func (se *SubscriptionEvent) setDeadline(margin time.Duration) {
+	if lockTime, ok := taskLockTime(se.Task); ok {
+		se.deadline = lockTime.Add(-margin)
+	}
+}
+
+// taskLockTime returns the time until which the task is locked, ok is false if the task isn't locked.
+func taskLockTime(task *zbmsgpack.Task) (lockTime time.Time, ok bool) {
+	if task == nil || task.LockTime == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(task.LockTime)*int64(time.Millisecond)), true
+}
+
+// WorkflowInstance returns the decoded event if it is a workflow instance event, otherwise nil.
+func (se *SubscriptionEvent) WorkflowInstance() *zbmsgpack.WorkflowInstance {
+	value, _ := se.Value.(*zbmsgpack.WorkflowInstance)
+	return value
+}
+
+// Deployment returns the decoded event if it is a deployment event, otherwise nil.
+func (se *SubscriptionEvent) Deployment() *zbmsgpack.Workflow {
+	value, _ := se.Value.(*zbmsgpack.Workflow)
+	return value
+}
+
+// Workflow returns the decoded event if it is a workflow event, otherwise nil.
+func (se *SubscriptionEvent) Workflow() *zbmsgpack.DeployedWorkflow {
+	value, _ := se.Value.(*zbmsgpack.DeployedWorkflow)
+	return value
+}
+
+// Incident returns the decoded event if it is an incident event, otherwise nil.
+func (se *SubscriptionEvent) Incident() *zbmsgpack.Incident {
+	value, _ := se.Value.(*zbmsgpack.Incident)
+	return value
+}
+
+// Topic returns the decoded event if it is a topic event, otherwise nil.
+func (se *SubscriptionEvent) Topic() *zbmsgpack.Topic {
+	value, _ := se.Value.(*zbmsgpack.Topic)
+	return value
+}
+
+// Raft returns the decoded event if it is a raft event, otherwise nil.
+func (se *SubscriptionEvent) Raft() *zbmsgpack.Raft {
+	value, _ := se.Value.(*zbmsgpack.Raft)
+	return value
+}
+
+// Subscriber returns the decoded event if it is a subscriber event, otherwise nil.
+func (se *SubscriptionEvent) Subscriber() *zbmsgpack.OpenTopicSubscription {
+	value, _ := se.Value.(*zbmsgpack.OpenTopicSubscription)
+	return value
+}
+
+// Subscription returns the decoded event if it is a subscription event, otherwise nil.
+func (se *SubscriptionEvent) Subscription() *zbmsgpack.TopicSubscriptionAck {
+	value, _ := se.Value.(*zbmsgpack.TopicSubscriptionAck)
+	return value
 }
 
 func (se *SubscriptionEvent) String() string {
 	b, _ := json.MarshalIndent(se, "", "  ")
 	return fmt.Sprintf("%+v", string(b))
 }
+
+// IncidentEvent is used on incident subscription. Key of the Event is the key of the incident.
+type IncidentEvent struct {
+	Incident *zbmsgpack.Incident
+	Event    *zbsbe.SubscribedEvent
+
+	event *SubscriptionEvent
+}
+
+// Ack marks the incident as processed, see SubscriptionEvent.Ack. It has to be called when the incident subscription
+// was opened WithManualAck.
+func (ie *IncidentEvent) Ack() {
+	if ie.event != nil {
+		ie.event.Ack()
+	}
+}
+
+func (ie *IncidentEvent) String() string {
+	b, _ := json.MarshalIndent(ie, "", "  ")
+	return fmt.Sprintf("%+v", string(b))
+}
@@ -0,0 +1,52 @@
+package zbc
+
+import (
+	"testing"
+	"time"
+)
+
+// lockedTaskEvent returns a task event locked until lockTime, with the deadline set as a task subscription does.
+func lockedTaskEvent(lockTime time.Time, margin time.Duration) *SubscriptionEvent {
+	event := newTaskEvent(3)
+	event.Task.LockTime = uint64(lockTime.UnixNano() / int64(time.Millisecond))
+	event.setDeadline(margin)
+	return event
+}
+
+func TestSubscriptionEventDeadline(t *testing.T) {
+	lockTime := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	event := lockedTaskEvent(lockTime, 5*time.Second)
+
+	deadline, ok := event.Deadline()
+	if !ok || !deadline.Equal(lockTime.Add(-5*time.Second)) {
+		t.Fatalf("expected deadline %s, got %s %v", lockTime.Add(-5*time.Second), deadline, ok)
+	}
+	if event.LockExpired() {
+		t.Fatal("lock expired before the deadline")
+	}
+}
+
+func TestSubscriptionEventLockExpiredWithinMargin(t *testing.T) {
+	// Task is still locked, but not for longer than the safety margin.
+	event := lockedTaskEvent(time.Now().Add(500*time.Millisecond), time.Second)
+	if !event.LockExpired() {
+		t.Fatal("expected lock to be expired within the safety margin")
+	}
+
+	event = lockedTaskEvent(time.Now().Add(-time.Second), 0)
+	if !event.LockExpired() {
+		t.Fatal("expected lock to be expired after the lock time")
+	}
+}
+
+func TestSubscriptionEventWithoutLock(t *testing.T) {
+	event := newTaskEvent(3)
+	event.setDeadline(time.Second)
+
+	if _, ok := event.Deadline(); ok {
+		t.Fatal("unexpected deadline for a task without lock time")
+	}
+	if event.LockExpired() {
+		t.Fatal("lock of a task without lock time expired")
+	}
+}
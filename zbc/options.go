@@ -0,0 +1,225 @@
+package zbc
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	errNoBootstrapBrokers     = errors.New("at least one bootstrap broker is required")
+	errInvalidRequestTimeout  = errors.New("request timeout must be positive")
+	errInvalidBackoff         = errors.New("backoff min must be positive and not greater than backoff max")
+	errInvalidBackoffDeadline = errors.New("backoff deadline must be positive")
+	errInvalidRefreshInterval = errors.New("topology refresh interval must be positive")
+	errInvalidSocketChunkSize = errors.New("socket chunk size must be positive")
+	errInvalidTaskCredits     = errors.New("task subscription credits must be positive")
+	errInvalidLockDuration    = errors.New("task lock duration must be at least one millisecond")
+	errInvalidCreditThreshold = errors.New("task credit threshold must be positive and not greater than task credits")
+	errInvalidSafetyMargin    = errors.New("task lock safety margin must not be negative")
+	errInvalidRateLimit       = errors.New("rate limit must have a positive rate and burst")
+)
+
+// clientConfig holds all tunable settings of the client. Defaults are taken from the package constants.
+type clientConfig struct {
+	bootstrapAddrs          []string
+	requestTimeout          time.Duration
+	backoffMin              time.Duration
+	backoffMax              time.Duration
+	backoffDeadline         time.Duration
+	topologyRefreshInterval time.Duration
+	socketChunkSize         int
+	taskCredits             int32
+	taskCreditThreshold     int32
+	creditPolicy            CreditPolicy
+	lockDuration            time.Duration
+	lockSafetyMargin        time.Duration
+	reconnectHandler        func(TaskSubscriptionReconnect)
+	taskRateLimits          map[string]rateLimit
+	commandRateLimits       map[string]rateLimit
+	deadLetterSink          DeadLetterSink
+}
+
+func (cfg *clientConfig) validate() error {
+	if len(cfg.bootstrapAddrs) == 0 {
+		return errNoBootstrapBrokers
+	}
+	for _, addr := range cfg.bootstrapAddrs {
+		if len(addr) == 0 {
+			return errNoBootstrapBrokers
+		}
+	}
+	if cfg.requestTimeout <= 0 {
+		return errInvalidRequestTimeout
+	}
+	if cfg.backoffMin <= 0 || cfg.backoffMin > cfg.backoffMax {
+		return errInvalidBackoff
+	}
+	if cfg.backoffDeadline <= 0 {
+		return errInvalidBackoffDeadline
+	}
+	if cfg.topologyRefreshInterval <= 0 {
+		return errInvalidRefreshInterval
+	}
+	if cfg.socketChunkSize <= 0 {
+		return errInvalidSocketChunkSize
+	}
+	if cfg.taskCredits <= 0 {
+		return errInvalidTaskCredits
+	}
+	if cfg.lockDuration < time.Millisecond {
+		return errInvalidLockDuration
+	}
+	if cfg.taskCreditThreshold < 0 || cfg.taskCreditThreshold > cfg.taskCredits {
+		return errInvalidCreditThreshold
+	}
+	if cfg.lockSafetyMargin < 0 {
+		return errInvalidSafetyMargin
+	}
+	for _, limits := range []map[string]rateLimit{cfg.taskRateLimits, cfg.commandRateLimits} {
+		for _, limit := range limits {
+			if limit.rate <= 0 || limit.burst < 1 {
+				return errInvalidRateLimit
+			}
+		}
+	}
+	return nil
+}
+
+// creditConfig returns the credit settings for a task subscription opened with the given credits.
+func (cfg *clientConfig) creditConfig(credits int32, policy CreditPolicy) creditConfig {
+	if policy == nil {
+		policy = cfg.creditPolicy
+	}
+	if policy == nil {
+		policy = FixedCreditPolicy{}
+	}
+	return creditConfig{credits: credits, threshold: cfg.taskCreditThreshold, policy: policy}
+}
+
+func newClientConfig() *clientConfig {
+	return &clientConfig{
+		requestTimeout:          RequestTimeout * time.Second,
+		backoffMin:              BackoffMin,
+		backoffMax:              BackoffMax,
+		backoffDeadline:         BackoffDeadline,
+		topologyRefreshInterval: TopologyRefreshInterval * time.Second,
+		socketChunkSize:         SocketChunkSize,
+		taskCredits:             TaskSubscriptionCredits,
+		lockDuration:            TaskSubscriptionLockDuration,
+		lockSafetyMargin:        TaskLockSafetyMargin,
+		reconnectHandler:        func(TaskSubscriptionReconnect) {},
+		taskRateLimits:          make(map[string]rateLimit),
+		commandRateLimits:       make(map[string]rateLimit),
+	}
+}
+
+// ClientOption is used to configure the client on construction.
+type ClientOption func(*clientConfig)
+
+// WithBootstrapBrokers adds brokers which are tried in the given order, after the address passed to NewClient, when
+// fetching the initial topology or when none of the brokers from the cached topology is reachable.
+func WithBootstrapBrokers(addrs ...string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.bootstrapAddrs = append(cfg.bootstrapAddrs, addrs...)
+	}
+}
+
+// WithRequestTimeout sets the timeout which is applied to requests whose context has no deadline.
+func WithRequestTimeout(timeout time.Duration) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.requestTimeout = timeout
+	}
+}
+
+// WithBackoff sets the bounds of the backoff used when writing to the socket fails and the deadline after which we give up.
+func WithBackoff(min, max, deadline time.Duration) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.backoffMin = min
+		cfg.backoffMax = max
+		cfg.backoffDeadline = deadline
+	}
+}
+
+// WithTopologyRefreshInterval sets how often the cluster topology is refreshed in the background.
+func WithTopologyRefreshInterval(interval time.Duration) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.topologyRefreshInterval = interval
+	}
+}
+
+// WithSocketChunkSize sets the number of bytes which are read from the connection at once.
+func WithSocketChunkSize(size int) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.socketChunkSize = size
+	}
+}
+
+// WithTaskCredits sets the number of credits with which every partition of a task subscription is opened.
+func WithTaskCredits(credits int32) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.taskCredits = credits
+	}
+}
+
+// WithCreditThreshold sets the number of remaining credits of a task subscription partition below which the used
+// credits are given back to the broker. By default this happens when half of the capacity is used up.
+func WithCreditThreshold(threshold int32) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.taskCreditThreshold = threshold
+	}
+}
+
+// WithCreditPolicy sets the policy which decides how many credits every partition of a task subscription holds.
+// By default the capacity stays at the credits with which the subscription was opened.
+func WithCreditPolicy(policy CreditPolicy) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.creditPolicy = policy
+	}
+}
+
+// WithLockDuration sets for how long tasks are locked to the lock owner of a task subscription.
+func WithLockDuration(duration time.Duration) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.lockDuration = duration
+	}
+}
+
+// WithLockSafetyMargin sets how long before the lock time of a task its deadline passes, leaving time to complete it.
+func WithLockSafetyMargin(margin time.Duration) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.lockSafetyMargin = margin
+	}
+}
+
+// WithReconnectHandler sets the function which is called when a partition of a task subscription is reopened, or
+// reopening it failed. It is called from the goroutine delivering the tasks of the partition and must not block.
+func WithReconnectHandler(handler func(TaskSubscriptionReconnect)) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.reconnectHandler = handler
+	}
+}
+
+// WithTaskRateLimit limits how many tasks of taskType are handed to consumers per second, allowing bursts of up to
+// burst tasks. The partitions of a task subscription for the type split burst between them as credits, though every
+// partition holds at least one.
+func WithTaskRateLimit(taskType string, rate float64, burst int) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.taskRateLimits[taskType] = rateLimit{rate: rate, burst: burst}
+	}
+}
+
+// WithCommandRateLimit limits how many commands are sent per second on topic, allowing bursts of up to burst commands.
+// It applies to creating tasks, workflows and workflow instances, commands on workflow instances and resolving incidents.
+func WithCommandRateLimit(topic string, rate float64, burst int) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.commandRateLimits[topic] = rateLimit{rate: rate, burst: burst}
+	}
+}
+
+// WithDeadLetterSink sets the sink in which tasks are stored when they are failed with no retries left, since the
+// broker raises an incident for them instead of handing them out again.
+func WithDeadLetterSink(sink DeadLetterSink) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.deadLetterSink = sink
+	}
+}
@@ -0,0 +1,127 @@
+package zbc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientConfigDefaults(t *testing.T) {
+	config := newClientConfig()
+	config.bootstrapAddrs = []string{"127.0.0.1:51015"}
+	if err := config.validate(); err != nil {
+		t.Fatalf("defaults are invalid: %s", err)
+	}
+	if config.requestTimeout != RequestTimeout*time.Second || config.taskCredits != TaskSubscriptionCredits || config.lockDuration != TaskSubscriptionLockDuration ||
+		config.lockSafetyMargin != TaskLockSafetyMargin {
+		t.Fatalf("unexpected defaults %+v", config)
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	config := newClientConfig()
+	config.bootstrapAddrs = []string{"127.0.0.1:51015"}
+	for _, opt := range []ClientOption{
+		WithBootstrapBrokers("127.0.0.1:51016", "127.0.0.1:51017"),
+		WithRequestTimeout(2 * time.Second),
+		WithBackoff(time.Millisecond, time.Second, time.Minute),
+		WithTopologyRefreshInterval(time.Minute),
+		WithSocketChunkSize(1024),
+		WithTaskCredits(8),
+		WithCreditThreshold(3),
+		WithCreditPolicy(NewAdaptiveCreditPolicy(1, 16)),
+		WithLockDuration(time.Second),
+		WithLockSafetyMargin(100 * time.Millisecond),
+		WithTaskRateLimit("foo", 10, 5),
+		WithCommandRateLimit("default-topic", 100, 20),
+	} {
+		opt(config)
+	}
+
+	if err := config.validate(); err != nil {
+		t.Fatalf("valid options rejected: %s", err)
+	}
+	if config.requestTimeout != 2*time.Second || config.backoffMin != time.Millisecond || config.backoffMax != time.Second ||
+		config.backoffDeadline != time.Minute || config.topologyRefreshInterval != time.Minute || config.socketChunkSize != 1024 ||
+		config.taskCredits != 8 || config.taskCreditThreshold != 3 || config.lockDuration != time.Second ||
+		config.lockSafetyMargin != 100*time.Millisecond {
+		t.Fatalf("options not applied %+v", config)
+	}
+	if len(config.bootstrapAddrs) != 3 || config.bootstrapAddrs[2] != "127.0.0.1:51017" {
+		t.Fatalf("unexpected bootstrap brokers %v", config.bootstrapAddrs)
+	}
+	if config.taskRateLimits["foo"] != (rateLimit{rate: 10, burst: 5}) || config.commandRateLimits["default-topic"] != (rateLimit{rate: 100, burst: 20}) {
+		t.Fatalf("unexpected rate limits %v %v", config.taskRateLimits, config.commandRateLimits)
+	}
+}
+
+func TestClientConfigCreditPolicy(t *testing.T) {
+	config := newClientConfig()
+	if _, ok := config.creditConfig(8, nil).policy.(FixedCreditPolicy); !ok {
+		t.Fatal("expected fixed credit policy by default")
+	}
+
+	adaptive := NewAdaptiveCreditPolicy(1, 16)
+	WithCreditPolicy(adaptive)(config)
+	if policy := config.creditConfig(8, nil).policy; policy != adaptive {
+		t.Fatalf("expected configured policy, got %v", policy)
+	}
+
+	// Policy of the subscription overrides the one of the client.
+	if _, ok := config.creditConfig(8, FixedCreditPolicy{}).policy.(FixedCreditPolicy); !ok {
+		t.Fatal("expected policy of the subscription")
+	}
+}
+
+func TestClientConfigReconnectHandler(t *testing.T) {
+	config := newClientConfig()
+	// Default handler ignores reconnects.
+	config.reconnectHandler(TaskSubscriptionReconnect{})
+
+	var reconnects []TaskSubscriptionReconnect
+	WithReconnectHandler(func(reconnect TaskSubscriptionReconnect) {
+		reconnects = append(reconnects, reconnect)
+	})(config)
+	config.reconnectHandler(TaskSubscriptionReconnect{PartitionID: 1, SubscriberKey: 20})
+	if len(reconnects) != 1 || reconnects[0].SubscriberKey != 20 {
+		t.Fatalf("unexpected reconnects %+v", reconnects)
+	}
+}
+
+func TestClientOptionsInvalid(t *testing.T) {
+	tests := []struct {
+		option ClientOption
+		err    error
+	}{
+		{WithBootstrapBrokers(""), errNoBootstrapBrokers},
+		{WithRequestTimeout(0), errInvalidRequestTimeout},
+		{WithRequestTimeout(-time.Second), errInvalidRequestTimeout},
+		{WithBackoff(0, time.Second, time.Minute), errInvalidBackoff},
+		{WithBackoff(2*time.Second, time.Second, time.Minute), errInvalidBackoff},
+		{WithBackoff(time.Millisecond, time.Second, -time.Minute), errInvalidBackoffDeadline},
+		{WithTopologyRefreshInterval(0), errInvalidRefreshInterval},
+		{WithSocketChunkSize(0), errInvalidSocketChunkSize},
+		{WithTaskCredits(0), errInvalidTaskCredits},
+		{WithTaskCredits(-1), errInvalidTaskCredits},
+		{WithLockDuration(time.Microsecond), errInvalidLockDuration},
+		{WithLockSafetyMargin(-time.Second), errInvalidSafetyMargin},
+		{WithCreditThreshold(-1), errInvalidCreditThreshold},
+		{WithCreditThreshold(TaskSubscriptionCredits + 1), errInvalidCreditThreshold},
+		{WithTaskRateLimit("foo", 0, 5), errInvalidRateLimit},
+		{WithTaskRateLimit("foo", 10, 0), errInvalidRateLimit},
+		{WithCommandRateLimit("default-topic", -1, 5), errInvalidRateLimit},
+	}
+
+	for i, test := range tests {
+		// Options are validated before the client connects to the broker.
+		if _, err := NewClient("127.0.0.1:0", test.option); err != test.err {
+			t.Errorf("case %d: expected %v, got %v", i, test.err, err)
+		}
+	}
+
+	if _, err := NewClient("127.0.0.1:0", WithTaskCredits(2), WithCreditThreshold(3)); err != errInvalidCreditThreshold {
+		t.Fatalf("expected errInvalidCreditThreshold, got %v", err)
+	}
+	if _, err := NewClient(""); err != errNoBootstrapBrokers {
+		t.Fatalf("expected errNoBootstrapBrokers, got %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package zbc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimitState is the state of a token bucket which limits tasks of a task type or commands on a topic.
+type RateLimitState struct {
+	// Rate is the number of tokens which are added per second.
+	Rate float64
+	// Burst is the maximum number of tokens in the bucket.
+	Burst int
+	// Tokens is the number of tokens which are currently available.
+	Tokens float64
+	// Waiting is the number of callers which currently wait for a token.
+	Waiting int
+	// Throttled is the number of times a caller had to wait for a token.
+	Throttled uint64
+}
+
+// tokenBucket allows rate events per second with bursts of up to burst events. Waiters are woken up when the limit
+// is changed, a bucket whose rate is zero lets everybody through.
+type tokenBucket struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     int
+	tokens    float64
+	last      time.Time
+	waiting   int
+	throttled uint64
+	changedCh chan struct{}
+}
+
+func (b *tokenBucket) refillLocked(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+	b.last = now
+}
+
+// wait will take a token, waiting until one is available or ctx is done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	throttled := false
+	for {
+		b.mu.Lock()
+		b.refillLocked(time.Now())
+		if b.rate <= 0 || b.tokens >= 1 {
+			if b.rate > 0 {
+				b.tokens--
+			}
+			if throttled {
+				b.waiting--
+			}
+			b.mu.Unlock()
+			return nil
+		}
+
+		if !throttled {
+			throttled = true
+			b.waiting++
+			b.throttled++
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		changedCh := b.changedCh
+		b.mu.Unlock()
+
+		select {
+		case <-time.After(delay):
+		case <-changedCh:
+		case <-ctx.Done():
+			b.mu.Lock()
+			b.waiting--
+			b.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+}
+
+func (b *tokenBucket) setLimit(rate float64, burst int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refillLocked(time.Now())
+	b.rate = rate
+	b.burst = burst
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	close(b.changedCh)
+	b.changedCh = make(chan struct{})
+}
+
+func (b *tokenBucket) state() RateLimitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refillLocked(time.Now())
+	return RateLimitState{
+		Rate:      b.rate,
+		Burst:     b.burst,
+		Tokens:    b.tokens,
+		Waiting:   b.waiting,
+		Throttled: b.throttled,
+	}
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:      rate,
+		burst:     burst,
+		tokens:    float64(burst),
+		last:      time.Now(),
+		changedCh: make(chan struct{}),
+	}
+}
+
+// rateLimit is a limit which is configured on construction of the client.
+type rateLimit struct {
+	rate  float64
+	burst int
+}
+
+// rateLimits holds the token buckets by task type or topic. Keys without a bucket aren't limited.
+type rateLimits struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func (rl *rateLimits) bucket(key string) *tokenBucket {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.buckets[key]
+}
+
+// set will change the limit of key, a rate which isn't positive removes it.
+func (rl *rateLimits) set(key string, rate float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+
+	rl.mu.Lock()
+	bucket, ok := rl.buckets[key]
+	if rate <= 0 {
+		delete(rl.buckets, key)
+	} else if !ok {
+		rl.buckets[key] = newTokenBucket(rate, burst)
+	}
+	rl.mu.Unlock()
+
+	if ok {
+		bucket.setLimit(rate, burst)
+	}
+}
+
+// wait will take a token of key, waiting until one is available or ctx is done.
+func (rl *rateLimits) wait(ctx context.Context, key string) error {
+	if bucket := rl.bucket(key); bucket != nil {
+		return bucket.wait(ctx)
+	}
+	return nil
+}
+
+// burst returns the burst of key, or zero if it isn't limited.
+func (rl *rateLimits) burst(key string) int32 {
+	if bucket := rl.bucket(key); bucket != nil {
+		return int32(bucket.state().Burst)
+	}
+	return 0
+}
+
+// partitionBurst splits the burst of key between the given number of partitions, so that together they never hold
+// more than the burst. Every partition gets at least one. It returns zero if key isn't limited.
+func (rl *rateLimits) partitionBurst(key string, partitions int) int32 {
+	burst := rl.burst(key)
+	if burst == 0 || partitions < 1 {
+		return burst
+	}
+	share := burst / int32(partitions)
+	if share < 1 {
+		share = 1
+	}
+	return share
+}
+
+func (rl *rateLimits) state() map[string]RateLimitState {
+	rl.mu.Lock()
+	buckets := make(map[string]*tokenBucket, len(rl.buckets))
+	for key, bucket := range rl.buckets {
+		buckets[key] = bucket
+	}
+	rl.mu.Unlock()
+
+	states := make(map[string]RateLimitState, len(buckets))
+	for key, bucket := range buckets {
+		states[key] = bucket.state()
+	}
+	return states
+}
+
+func newRateLimits(limits map[string]rateLimit) *rateLimits {
+	rl := &rateLimits{buckets: make(map[string]*tokenBucket)}
+	for key, limit := range limits {
+		rl.set(key, limit.rate, limit.burst)
+	}
+	return rl
+}
@@ -0,0 +1,192 @@
+package zbc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// waitForWaiting polls the bucket until n callers wait for a token.
+func waitForWaiting(t *testing.T, b *tokenBucket, n int) {
+	deadline := time.Now().Add(time.Second)
+	for b.state().Waiting != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiting, got %d", n, b.state().Waiting)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTokenBucketBurst(t *testing.T) {
+	b := newTokenBucket(1, 3)
+	for i := 0; i < 3; i++ {
+		if err := b.wait(context.Background()); err != nil {
+			t.Fatalf("wait %d failed: %s", i, err)
+		}
+	}
+	if state := b.state(); state.Throttled != 0 || state.Tokens >= 1 {
+		t.Fatalf("unexpected state after burst %+v", state)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if state := b.state(); state.Waiting != 0 || state.Throttled != 1 {
+		t.Fatalf("unexpected state after timeout %+v", state)
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	b := newTokenBucket(50, 1)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := b.wait(context.Background()); err != nil {
+			t.Fatalf("wait %d failed: %s", i, err)
+		}
+	}
+
+	// First token is in the bucket, the other three are added every 20ms.
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("tokens were refilled too fast, took %s", elapsed)
+	}
+	if state := b.state(); state.Throttled != 3 || state.Waiting != 0 {
+		t.Fatalf("unexpected state %+v", state)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if state := b.state(); state.Tokens != 1 {
+		t.Fatalf("expected tokens to be capped at the burst, got %f", state.Tokens)
+	}
+}
+
+func TestTokenBucketSetLimitWakesWaiters(t *testing.T) {
+	b := newTokenBucket(0.01, 1)
+	b.wait(context.Background())
+
+	errCh := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errCh <- b.wait(context.Background())
+		}()
+	}
+	waitForWaiting(t, b, 2)
+
+	b.setLimit(1000, 5)
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errCh:
+			if err != nil {
+				t.Fatalf("wait failed: %s", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("waiter wasn't woken up by the new limit")
+		}
+	}
+
+	state := b.state()
+	if state.Rate != 1000 || state.Burst != 5 || state.Waiting != 0 || state.Throttled != 2 {
+		t.Fatalf("unexpected state %+v", state)
+	}
+}
+
+func TestTokenBucketSetLimitCapsTokens(t *testing.T) {
+	b := newTokenBucket(1, 10)
+	b.setLimit(1, 2)
+	if state := b.state(); state.Tokens > 2 {
+		t.Fatalf("expected tokens to be capped at the new burst, got %f", state.Tokens)
+	}
+}
+
+func TestTokenBucketCancel(t *testing.T) {
+	b := newTokenBucket(0.01, 1)
+	b.wait(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- b.wait(ctx)
+	}()
+	waitForWaiting(t, b, 1)
+
+	cancel()
+	if err := <-errCh; err != context.Canceled {
+		t.Fatalf("expected context canceled, got %v", err)
+	}
+	if state := b.state(); state.Waiting != 0 || state.Throttled != 1 {
+		t.Fatalf("unexpected state %+v", state)
+	}
+}
+
+func TestRateLimitsRemove(t *testing.T) {
+	rl := newRateLimits(map[string]rateLimit{"foo": {rate: 0.01, burst: 0}})
+	if burst := rl.burst("foo"); burst != 1 {
+		t.Fatalf("expected burst to be at least 1, got %d", burst)
+	}
+	if burst := rl.burst("bar"); burst != 0 {
+		t.Fatalf("expected no burst for unlimited key, got %d", burst)
+	}
+	rl.wait(context.Background(), "foo")
+
+	bucket := rl.bucket("foo")
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- rl.wait(context.Background(), "foo")
+	}()
+	waitForWaiting(t, bucket, 1)
+
+	// Removing the limit lets the waiter through as well.
+	rl.set("foo", 0, 0)
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("wait failed: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter wasn't woken up when the limit was removed")
+	}
+
+	if _, ok := rl.state()["foo"]; ok || rl.burst("foo") != 0 {
+		t.Fatal("limit wasn't removed")
+	}
+	for i := 0; i < 10; i++ {
+		if err := rl.wait(context.Background(), "foo"); err != nil {
+			t.Fatalf("wait without limit failed: %s", err)
+		}
+	}
+}
+
+func TestRateLimitsChange(t *testing.T) {
+	rl := newRateLimits(nil)
+	rl.set("foo", 10, 2)
+	rl.set("foo", 20, 4)
+
+	state, ok := rl.state()["foo"]
+	if !ok || state.Rate != 20 || state.Burst != 4 {
+		t.Fatalf("unexpected state %+v", state)
+	}
+}
+
+func TestRateLimitsPartitionBurst(t *testing.T) {
+	rl := newRateLimits(map[string]rateLimit{"foo": {rate: 10, burst: 8}})
+
+	tests := []struct {
+		key        string
+		partitions int
+		burst      int32
+	}{
+		{"foo", 1, 8},
+		{"foo", 2, 4},
+		{"foo", 3, 2},
+		{"foo", 8, 1},
+		{"foo", 16, 1},
+		{"bar", 2, 0},
+	}
+
+	for _, test := range tests {
+		if burst := rl.partitionBurst(test.key, test.partitions); burst != test.burst {
+			t.Errorf("partitionBurst(%s, %d): expected %d, got %d", test.key, test.partitions, test.burst, burst)
+		}
+	}
+}
@@ -126,18 +126,24 @@ func (mr *MessageReader) readMessage(data []byte) (*Message, error) {
 func (mr *MessageReader) readHeaders() (*Headers, *[]byte, error) {
 	var header Headers
 
-	headerByte := mr.getBytes(0, FrameHeaderSize)
+	headerByte, err := mr.getBytes(0, FrameHeaderSize)
+	if err != nil {
+		return nil, nil, err
+	}
 	frameHeader, err := mr.readFrameHeader(bytes.NewReader(headerByte))
-	frameHeader.Length = frameHeader.Length - FrameHeaderSize
-
 	if err != nil {
 		return nil, nil, err
 	}
+	frameHeader.Length = frameHeader.Length - FrameHeaderSize
+
 	if frameHeader.Length < TotalHeaderSizeNoFrame {
 		return nil, nil, errFrameHeaderRead
 	}
 	header.SetFrameHeader(frameHeader)
-	message := mr.getBytes(FrameHeaderSize, int(frameHeader.Length)+FrameHeaderSize)
+	message, err := mr.getBytes(FrameHeaderSize, int(frameHeader.Length)+FrameHeaderSize)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	if int(frameHeader.Length) != len(message) || len(message) == 0 {
 		return nil, nil, errFrameHeaderRead
@@ -219,6 +225,16 @@ func (mr *MessageReader) decodeCtlResponse(reader *bytes.Reader, header *zbsbe.M
 	return &controlResponse, nil
 }
 
+func (mr *MessageReader) decodeErrResponse(reader *bytes.Reader, header *zbsbe.MessageHeader) (*zbsbe.ErrorResponse, error) {
+	var errorResponse zbsbe.ErrorResponse
+	// FailedRequest holds the raw bytes of the request, so it can't pass the UTF-8 range check.
+	err := errorResponse.Decode(reader, binary.LittleEndian, header.Version, header.BlockLength, false)
+	if err != nil {
+		return nil, err
+	}
+	return &errorResponse, nil
+}
+
 func (mr *MessageReader) decodeSubEvent(reader *bytes.Reader, header *zbsbe.MessageHeader) (*zbsbe.SubscribedEvent, error) {
 	var subEvent zbsbe.SubscribedEvent
 	err := subEvent.Decode(reader, binary.LittleEndian, header.Version, header.BlockLength, true)
@@ -266,6 +282,16 @@ func (mr *MessageReader) parseMessage(headers *Headers, message *[]byte) (*Messa
 
 		break
 
+	case templateIDErrorResponse:
+		errorResponse, err := mr.decodeErrResponse(reader, headers.SbeMessageHeader)
+		if err != nil {
+			return nil, err
+		}
+		msg.SetSbeMessage(errorResponse)
+		msg.SetData([]byte(errorResponse.ErrorData))
+
+		break
+
 	case templateIDSubscriptionEvent:
 		subscribedEvent, err := mr.decodeSubEvent(reader, headers.SbeMessageHeader)
 		if err != nil {
@@ -1,6 +1,8 @@
 package zbc
 
 import (
+	"time"
+
 	"github.com/vmihailenco/msgpack"
 	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
 	"github.com/zeebe-io/zbc-go/zbc/zbprotocol"
@@ -112,13 +114,60 @@ func (rf *requestFactory) createTaskRequest(partition uint16, position uint64, t
 }
 
 func (rf *requestFactory) completeTaskRequest(taskMessage *SubscriptionEvent) *Message {
-	taskMessage.Task.State = TaskComplete
+	return rf.taskCommandRequest(taskMessage, taskCommand(taskMessage, TaskComplete))
+}
+
+func (rf *requestFactory) failTaskRequest(taskMessage *SubscriptionEvent) *Message {
+	task := taskCommand(taskMessage, TaskFail)
+	task.Retries--
+	return rf.taskCommandRequest(taskMessage, task)
+}
+
+func (rf *requestFactory) updateTaskRetriesRequest(taskMessage *SubscriptionEvent, retries int) *Message {
+	task := taskCommand(taskMessage, TaskUpdateRetries)
+	task.Retries = retries
+	return rf.taskCommandRequest(taskMessage, task)
+}
+
+func (rf *requestFactory) cancelTaskRequest(taskMessage *SubscriptionEvent) *Message {
+	return rf.taskCommandRequest(taskMessage, taskCommand(taskMessage, TaskCancel))
+}
+
+func (rf *requestFactory) taskCommandRequest(taskMessage *SubscriptionEvent, task *zbmsgpack.Task) *Message {
 	cmdReq := &zbsbe.ExecuteCommandRequest{
 		PartitionId: taskMessage.Event.PartitionId,
 		Position:    taskMessage.Event.Position,
 		Key:         taskMessage.Event.Key,
 	}
-	return rf.newCommandMessage(cmdReq, taskMessage.Task)
+	return rf.newCommandMessage(cmdReq, task)
+}
+
+// taskCommand returns a copy of the task in the given state, so the event of the caller stays untouched and the
+// command can be sent again.
+func taskCommand(taskMessage *SubscriptionEvent, state string) *zbmsgpack.Task {
+	task := *taskMessage.Task
+	task.State = state
+	return &task
+}
+
+func (rf *requestFactory) workflowInstanceCommandRequest(partition uint16, key uint64, wf *zbmsgpack.WorkflowInstance) *Message {
+	commandRequest := &zbsbe.ExecuteCommandRequest{
+		PartitionId: partition,
+		Position:    0,
+		Key:         key,
+		EventType:   zbsbe.EventType.WORKFLOW_INSTANCE_EVENT,
+	}
+	return rf.newCommandMessage(commandRequest, wf)
+}
+
+func (rf *requestFactory) resolveIncidentRequest(partition uint16, key uint64, incident *zbmsgpack.Incident) *Message {
+	commandRequest := &zbsbe.ExecuteCommandRequest{
+		PartitionId: partition,
+		Position:    0,
+		Key:         key,
+		EventType:   zbsbe.EventType.INCIDENT_EVENT,
+	}
+	return rf.newCommandMessage(commandRequest, incident)
 }
 
 func (rf *requestFactory) createWorkflowInstanceRequest(partition uint16, position uint64, topic string, wf *zbmsgpack.WorkflowInstance) *Message {
@@ -169,10 +218,10 @@ func (rf *requestFactory) deployWorkflowRequest(topic string, resources []*zbmsg
 	return rf.newCommandMessage(commandRequest, deployment)
 }
 
-func (rf *requestFactory) openTaskSubscriptionRequest(partitionId uint16, lockOwner, taskType string, credits int32) *Message {
+func (rf *requestFactory) openTaskSubscriptionRequest(partitionId uint16, lockOwner, taskType string, lockDuration time.Duration, credits int32) *Message {
 	taskSub := &zbmsgpack.TaskSubscription{
 		Credits:       credits,
-		LockDuration:  300000,
+		LockDuration:  uint64(lockDuration / time.Millisecond),
 		LockOwner:     lockOwner,
 		SubscriberKey: 0,
 		TaskType:      taskType,
@@ -203,6 +252,7 @@ func (rf *requestFactory) increaseTaskSubscriptionCreditsRequest(ts *zbmsgpack.T
 	}
 	controlRequest := &zbsbe.ControlMessageRequest{
 		MessageType: zbsbe.ControlMessageType.INCREASE_TASK_SUBSCRIPTION_CREDITS,
+		PartitionId: ts.PartitionID,
 		Data:        b,
 	}
 	msg.SetSbeMessage(controlRequest)
@@ -219,6 +269,7 @@ func (rf *requestFactory) closeTaskSubscriptionRequest(ts *zbmsgpack.TaskSubscri
 	}
 	controlRequest := &zbsbe.ControlMessageRequest{
 		MessageType: zbsbe.ControlMessageType.REMOVE_TASK_SUBSCRIPTION,
+		PartitionId: ts.PartitionID,
 		Data:        b,
 	}
 	msg.SetSbeMessage(controlRequest)
@@ -1,29 +1,50 @@
 package zbc
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
 	"github.com/zeebe-io/zbc-go/zbc/zbsbe"
 )
 
+// taskSubscriptionEntry holds the state which the client keeps for an open task subscription.
+type taskSubscriptionEntry struct {
+	doneCh  chan struct{}
+	wg      sync.WaitGroup
+	credits []*partitionCredits
+}
+
 type requestManager struct {
 	*requestFactory
 	*responseHandler
 
 	*topologyManager
+
+	subscriptionsMu    sync.Mutex
+	taskSubscriptions  map[*zbmsgpack.TaskSubscriptionInfo]*taskSubscriptionEntry
+	topicSubscriptions map[*zbmsgpack.TopicSubscriptionInfo]chan struct{}
+
+	taskLimits    *rateLimits
+	commandLimits *rateLimits
 }
 
-func (rm *requestManager) partitionRequest() (*zbmsgpack.PartitionCollection, error) {
+func (rm *requestManager) partitionRequest(ctx context.Context) (*zbmsgpack.PartitionCollection, error) {
 	message := rm.createPartitionRequest()
 	request := newRequestWrapper(message)
-	resp, err := rm.executeRequest(request)
+	resp, err := rm.executeRequest(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 	return rm.unmarshalPartition(resp), nil
 }
 
-func (rm *requestManager) createTask(topic string, task *zbmsgpack.Task) (*zbmsgpack.Task, error) {
-	partitionID, err := rm.partitionID(topic)
+func (rm *requestManager) createTask(ctx context.Context, topic string, task *zbmsgpack.Task) (*zbmsgpack.Task, error) {
+	if err := rm.commandLimits.wait(ctx, topic); err != nil {
+		return nil, err
+	}
+	partitionID, err := rm.partitionID(ctx, topic)
 
 	if err != nil {
 		return nil, err
@@ -31,25 +52,44 @@ func (rm *requestManager) createTask(topic string, task *zbmsgpack.Task) (*zbmsg
 
 	message := rm.createTaskRequest(partitionID, 0, task)
 	request := newRequestWrapper(message)
-	resp, err := rm.executeRequest(request)
+	resp, err := rm.executeRequest(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	return rm.unmarshalTask(resp), nil
+
+	createdTask := rm.unmarshalTask(resp)
+	if createdTask == nil {
+		return nil, nil
+	}
+	return createdTask, checkRejection(TaskCreate, createdTask.State, createdTask)
 }
 
-func (rm *requestManager) createWorkflow(topic string, resource []*zbmsgpack.Resource) (*zbmsgpack.Workflow, error) {
+func (rm *requestManager) createWorkflow(ctx context.Context, topic string, resource []*zbmsgpack.Resource) (*zbmsgpack.Workflow, error) {
+	if err := rm.commandLimits.wait(ctx, topic); err != nil {
+		return nil, err
+	}
 	message := rm.deployWorkflowRequest(topic, resource)
 	request := newRequestWrapper(message)
-	resp, err := rm.executeRequest(request)
+	resp, err := rm.executeRequest(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	return rm.unmarshalWorkflow(resp), nil
+
+	workflow := rm.unmarshalWorkflow(resp)
+	if workflow == nil {
+		return nil, nil
+	}
+	return workflow, checkRejection(CreateDeployment, workflow.State, workflow)
 }
 
-func (rm *requestManager) createWorkflowInstance(topic string, wfi *zbmsgpack.WorkflowInstance) (*zbmsgpack.WorkflowInstance, error) {
-	partitionID, err := rm.partitionID(topic)
+func (rm *requestManager) createWorkflowInstance(ctx context.Context, topic string, wfi *zbmsgpack.WorkflowInstance) (*zbmsgpack.WorkflowInstance, error) {
+	if wfi == nil {
+		return nil, errNoWorkflowInstance
+	}
+	if err := rm.commandLimits.wait(ctx, topic); err != nil {
+		return nil, err
+	}
+	partitionID, err := rm.partitionID(ctx, topic)
 
 	if err != nil {
 		return nil, err
@@ -57,66 +97,247 @@ func (rm *requestManager) createWorkflowInstance(topic string, wfi *zbmsgpack.Wo
 
 	message := rm.createWorkflowInstanceRequest(partitionID, 0, topic, wfi)
 	request := newRequestWrapper(message)
-	resp, err := rm.executeRequest(request)
+	resp, err := rm.executeRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	instance := rm.unmarshalWorkflowInstance(resp)
+	if instance == nil {
+		return nil, nil
+	}
+	return instance, checkRejection(CreateWorkflowInstance, instance.State, instance)
+}
+
+func (rm *requestManager) cancelWorkflowInstance(ctx context.Context, topic string, partitionID uint16, instanceKey uint64) (*zbmsgpack.WorkflowInstance, error) {
+	wfi := &zbmsgpack.WorkflowInstance{
+		State:               CancelWorkflowInstance,
+		WorkflowInstanceKey: instanceKey,
+	}
+	return rm.executeWorkflowInstanceCommand(ctx, topic, partitionID, instanceKey, wfi)
+}
+
+func (rm *requestManager) updateWorkflowInstancePayload(ctx context.Context, topic string, partitionID uint16, activityInstanceKey uint64, payload interface{}) (*zbmsgpack.WorkflowInstance, error) {
+	wfi := &zbmsgpack.WorkflowInstance{
+		State: UpdateWorkflowInstancePayload,
+	}
+	if err := wfi.SetPayload(payload); err != nil {
+		return nil, err
+	}
+	return rm.executeWorkflowInstanceCommand(ctx, topic, partitionID, activityInstanceKey, wfi)
+}
+
+func (rm *requestManager) executeWorkflowInstanceCommand(ctx context.Context, topic string, partitionID uint16, key uint64, wfi *zbmsgpack.WorkflowInstance) (*zbmsgpack.WorkflowInstance, error) {
+	if err := rm.commandLimits.wait(ctx, topic); err != nil {
+		return nil, err
+	}
+	request := newRequestWrapper(rm.workflowInstanceCommandRequest(partitionID, key, wfi))
+	resp, err := rm.executeRequest(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	return rm.unmarshalWorkflowInstance(resp), nil
+
+	instance := rm.unmarshalWorkflowInstance(resp)
+	if instance == nil {
+		return nil, nil
+	}
+	return instance, checkRejection(wfi.State, instance.State, instance)
 }
 
-func (rm *requestManager) completeTask(task *SubscriptionEvent) (*zbmsgpack.Task, error) {
-	message := rm.completeTaskRequest(task)
+func (rm *requestManager) resolveIncident(ctx context.Context, topic string, incidentEvent *IncidentEvent, payload interface{}) (*zbmsgpack.Incident, error) {
+	if incidentEvent == nil || incidentEvent.Event == nil {
+		return nil, errNoIncident
+	}
+
+	incident := &zbmsgpack.Incident{
+		State: IncidentResolve,
+	}
+	if err := incident.SetPayload(payload); err != nil {
+		return nil, err
+	}
+	if err := rm.commandLimits.wait(ctx, topic); err != nil {
+		return nil, err
+	}
+	request := newRequestWrapper(rm.resolveIncidentRequest(incidentEvent.Event.PartitionId, incidentEvent.Event.Key, incident))
+	resp, err := rm.executeRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := rm.unmarshalIncident(resp)
+	if resolved == nil {
+		return nil, nil
+	}
+	if resolved.State == IncidentResolveFailed {
+		return resolved, &RejectionError{Command: IncidentResolve, State: resolved.State, Event: resolved}
+	}
+	return resolved, checkRejection(IncidentResolve, resolved.State, resolved)
+}
+
+func (rm *requestManager) completeTask(ctx context.Context, task *SubscriptionEvent) (*zbmsgpack.Task, error) {
+	if task.LockExpired() {
+		return nil, ErrTaskLockExpired
+	}
+	return rm.executeTaskCommand(ctx, TaskComplete, rm.completeTaskRequest(task))
+}
+
+func (rm *requestManager) completeTaskWithPayload(ctx context.Context, task *SubscriptionEvent, payload interface{}, merge bool) (*zbmsgpack.Task, error) {
+	completed, err := taskWithPayload(task, payload, merge)
+	if err != nil {
+		return nil, err
+	}
+	return rm.completeTask(ctx, completed)
+}
+
+// taskWithPayload returns a copy of the event whose task carries the payload, the event of the caller stays untouched.
+func taskWithPayload(task *SubscriptionEvent, payload interface{}, merge bool) (*SubscriptionEvent, error) {
+	event := *task
+	withPayload := *task.Task
+	event.Task = &withPayload
+
+	var err error
+	if merge {
+		err = withPayload.MergePayload(payload)
+	} else {
+		err = withPayload.SetPayload(payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (rm *requestManager) failTask(ctx context.Context, task *SubscriptionEvent) (*zbmsgpack.Task, error) {
+	return rm.failTaskWithCause(ctx, task, nil)
+}
+
+// failTaskWithError will fail the task and store the error message in its custom headers.
+func (rm *requestManager) failTaskWithError(ctx context.Context, task *SubscriptionEvent, cause error) (*zbmsgpack.Task, error) {
+	return rm.failTaskWithCause(ctx, taskWithError(task, cause), cause)
+}
+
+// taskWithError returns a copy of the event whose task carries the error message in its custom headers, the event of
+// the caller stays untouched.
+func taskWithError(task *SubscriptionEvent, cause error) *SubscriptionEvent {
+	event := *task
+	withError := *task.Task
+	event.Task = &withError
+
+	withError.CustomHeader = make(map[string]interface{}, len(task.Task.CustomHeader)+1)
+	for key, value := range task.Task.CustomHeader {
+		withError.CustomHeader[key] = value
+	}
+	withError.CustomHeader[TaskErrorMessageHeader] = cause.Error()
+	return &event
+}
+
+// failTaskWithCause will fail the task and, if no retries are left, store it in the dead-letter sink.
+func (rm *requestManager) failTaskWithCause(ctx context.Context, task *SubscriptionEvent, cause error) (*zbmsgpack.Task, error) {
+	failed, err := rm.executeTaskCommand(ctx, TaskFail, rm.failTaskRequest(task))
+	if err != nil {
+		return failed, err
+	}
+	return failed, rm.deadLetter(task, cause)
+}
+
+func (rm *requestManager) updateTaskRetries(ctx context.Context, task *SubscriptionEvent, retries int) (*zbmsgpack.Task, error) {
+	if retries <= 0 {
+		return nil, errInvalidRetries
+	}
+	return rm.executeTaskCommand(ctx, TaskUpdateRetries, rm.updateTaskRetriesRequest(task, retries))
+}
+
+func (rm *requestManager) cancelTask(ctx context.Context, task *SubscriptionEvent) (*zbmsgpack.Task, error) {
+	return rm.executeTaskCommand(ctx, TaskCancel, rm.cancelTaskRequest(task))
+}
+
+// executeTaskCommand will send the command on a task received through a subscription and decode the resulting task event.
+func (rm *requestManager) executeTaskCommand(ctx context.Context, command string, message *Message) (*zbmsgpack.Task, error) {
 	request := newRequestWrapper(message)
-	resp, err := rm.executeRequest(request)
+	resp, err := rm.executeRequest(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	return rm.unmarshalTask(resp), nil
+
+	task := rm.unmarshalTask(resp)
+	if task == nil {
+		return nil, nil
+	}
+	return task, checkRejection(command, task.State, task)
 }
 
-func (rm *requestManager) taskConsumer(topic, lockOwner, taskType string, credits int32) (chan *SubscriptionEvent, *zbmsgpack.TaskSubscriptionInfo, error) {
+// taskConsumer opens the task subscription on every partition of the topic. A credit of the partition is used up when
+// the task is handed to the consumer and the credits are replenished as decided by the credit policy. Partitions are
+// reopened on the new leader when their socket is lost or the leader moves. When tasks of taskType are rate limited,
+// they are handed to the consumer only as fast as the limit allows and the credits of all partitions together never
+// exceed its burst.
+func (rm *requestManager) taskConsumer(ctx context.Context, topic, lockOwner, taskType string, lockDuration time.Duration, credits creditConfig) (chan *SubscriptionEvent, *zbmsgpack.TaskSubscriptionInfo, error) {
 	partitions, err := rm.topicPartitionsAddrs(topic)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	send := func(endSubscriptionCh chan *SubscriptionEvent, subscriptionCh <-chan *SubscriptionEvent) {
-		for {
-			msg := <-subscriptionCh
-			endSubscriptionCh <- msg
-		}
+	// Never lock more tasks than the rate limit lets through at once, the burst is split between the partitions.
+	opened := credits.credits
+	if limit := rm.taskLimits.partitionBurst(taskType, len(*partitions)); limit > 0 && limit < opened {
+		opened = limit
 	}
 
 	tsi := zbmsgpack.NewTaskSubscriptionInfo()
 	endSubscriptionCh := make(chan *SubscriptionEvent)
+	entry := rm.addTaskSubscriptionInfo(tsi)
 
 	for partitionID := range *partitions {
-		subscriptionCh := make(chan *SubscriptionEvent, credits)
-		message := rm.openTaskSubscriptionRequest(partitionID, lockOwner, taskType, credits)
+		subscriptionCh := make(chan *SubscriptionEvent, credits.policy.Limit(credits.credits))
+		message := rm.openTaskSubscriptionRequest(partitionID, lockOwner, taskType, lockDuration, opened)
 		request := newRequestWrapper(message)
-		resp, err := rm.executeRequest(request)
+		resp, err := rm.executeRequest(ctx, request)
 		if err != nil {
+			rm.closeTaskSubscription(context.Background(), tsi)
 			return nil, nil, err
 		}
 
 		taskSubInfo := rm.unmarshalTaskSubscription(resp)
 		if taskSubInfo != nil {
-			tsi.AddSubInfo(*taskSubInfo)
-			request.sock.addTaskSubscription(taskSubInfo.SubscriberKey, subscriptionCh)
-			go send(endSubscriptionCh, subscriptionCh)
+			taskSubInfo.PartitionID = partitionID
+			taskSubInfo.Credits = opened
+			pc := newPartitionCredits(*taskSubInfo, lockDuration, credits)
+			ps := &partitionSubscription{
+				rm:           rm,
+				tsi:          tsi,
+				entry:        entry,
+				index:        rm.addTaskSubscriptionPartition(tsi, entry, *taskSubInfo, pc),
+				lockOwner:    lockOwner,
+				taskType:     taskType,
+				lockDuration: lockDuration,
+				credits:      pc,
+				partitions:   len(*partitions),
+				sock:         request.socket(),
+				addr:         request.addr,
+				eventsCh:     subscriptionCh,
+			}
+			ps.open, ps.close = ps.openPartition, ps.closePartition
+			ps.sock.addTaskSubscription(taskSubInfo.SubscriberKey, subscriptionCh)
+			entry.wg.Add(1)
+			go ps.run(endSubscriptionCh)
 		}
 
 	}
 
+	go func() {
+		entry.wg.Wait()
+		close(endSubscriptionCh)
+	}()
+
 	return endSubscriptionCh, tsi, nil
 }
 
-func (rm *requestManager) increaseTaskSubscriptionCredits(task *zbmsgpack.TaskSubscription) (*zbmsgpack.TaskSubscription, error) {
+func (rm *requestManager) increaseTaskSubscriptionCredits(ctx context.Context, task *zbmsgpack.TaskSubscription) (*zbmsgpack.TaskSubscription, error) {
 	message := rm.increaseTaskSubscriptionCreditsRequest(task)
 
 	request := newRequestWrapper(message)
 
-	resp, err := rm.executeRequest(request)
+	resp, err := rm.executeRequest(ctx, request)
 	if err != nil {
 		return nil, err
 	}
@@ -124,10 +345,10 @@ func (rm *requestManager) increaseTaskSubscriptionCredits(task *zbmsgpack.TaskSu
 	return rm.unmarshalTaskSubscription(resp), nil
 }
 
-func (rm *requestManager) closeTaskSubscription(sub *zbmsgpack.TaskSubscriptionInfo) []error {
+func (rm *requestManager) closeTaskSubscription(ctx context.Context, sub *zbmsgpack.TaskSubscriptionInfo) []error {
 	var errs []error
-	for _, taskSub := range sub.Subs {
-		_, err := rm.closeTaskSubscriptionPartition(&taskSub)
+	for _, taskSub := range rm.removeTaskSubscriptionInfo(sub) {
+		_, err := rm.closeTaskSubscriptionPartition(ctx, &taskSub)
 		if err != nil {
 			errs = append(errs, err)
 		}
@@ -139,17 +360,22 @@ func (rm *requestManager) closeTaskSubscription(sub *zbmsgpack.TaskSubscriptionI
 	return nil
 }
 
-func (rm *requestManager) closeTaskSubscriptionPartition(task *zbmsgpack.TaskSubscription) (*Message, error) {
+func (rm *requestManager) closeTaskSubscriptionPartition(ctx context.Context, task *zbmsgpack.TaskSubscription) (*Message, error) {
 	message := rm.closeTaskSubscriptionRequest(task)
 	request := newRequestWrapper(message)
-	resp, err := rm.executeRequest(request)
-	request.sock.removeTaskSubscription(task.SubscriberKey)
+	resp, err := rm.executeRequest(ctx, request)
+	if sock := request.socket(); sock != nil {
+		sock.removeTaskSubscription(task.SubscriberKey)
+	}
 	return resp, err
 }
-func (rm *requestManager) closeTopicSubscription(sub *zbmsgpack.TopicSubscriptionInfo) []error {
+
+func (rm *requestManager) closeTopicSubscription(ctx context.Context, sub *zbmsgpack.TopicSubscriptionInfo) []error {
+	rm.removeTopicSubscriptionInfo(sub)
+
 	var errs []error
 	for _, taskSub := range sub.Subs {
-		_, err := rm.closeTopicSubscriptionPartition(&taskSub)
+		_, err := rm.closeTopicSubscriptionPartition(ctx, &taskSub)
 		if err != nil {
 			errs = append(errs, err)
 		}
@@ -161,58 +387,95 @@ func (rm *requestManager) closeTopicSubscription(sub *zbmsgpack.TopicSubscriptio
 	return nil
 }
 
-func (rm *requestManager) closeTopicSubscriptionPartition(topicPartition *zbmsgpack.TopicSubscription) (*Message, error) {
+func (rm *requestManager) closeTopicSubscriptionPartition(ctx context.Context, topicPartition *zbmsgpack.TopicSubscription) (*Message, error) {
 	message := rm.closeTopicSubscriptionRequest(topicPartition)
 	request := newRequestWrapper(message)
-	resp, err := rm.executeRequest(request)
+	resp, err := rm.executeRequest(ctx, request)
+	if sock := request.socket(); sock != nil {
+		sock.removeTopicSubscription(topicPartition.SubscriberKey)
+	}
 	return resp, err
 }
 
-func (rm *requestManager) topicSubscriptionAck(ts *zbmsgpack.TopicSubscription, s *SubscriptionEvent) (*zbmsgpack.TopicSubscriptionAck, error) {
+func (rm *requestManager) topicSubscriptionAck(ctx context.Context, ts *zbmsgpack.TopicSubscription, s *SubscriptionEvent) (*zbmsgpack.TopicSubscriptionAck, error) {
 	message := rm.topicSubscriptionAckRequest(ts, s)
 	request := newRequestWrapper(message)
-	resp, err := rm.executeRequest(request)
-	return rm.unmarshalTopicSubAck(resp), err
+	resp, err := rm.executeRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	ack := rm.unmarshalTopicSubAck(resp)
+	if ack == nil {
+		return nil, nil
+	}
+	return ack, checkRejection(TopicSubscriptionAckState, ack.State, ack)
 }
 
-func (rm *requestManager) createTopic(name string, partitionNum int) (*zbmsgpack.Topic, error) {
+func (rm *requestManager) createTopic(ctx context.Context, name string, partitionNum int) (*zbmsgpack.Topic, error) {
 	topic := zbmsgpack.NewTopic(name, TopicCreate, partitionNum)
 	message := rm.createTopicRequest(topic)
 	request := newRequestWrapper(message)
 
-	resp, err := rm.executeRequest(request)
+	resp, err := rm.executeRequest(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	return rm.unmarshalTopic(resp), nil
+
+	createdTopic := rm.unmarshalTopic(resp)
+	return createdTopic, checkRejection(TopicCreate, createdTopic.State, createdTopic)
 }
 
-func (rm *requestManager) topicConsumer(topic, subName string, startPosition int64) (chan *SubscriptionEvent, *zbmsgpack.TopicSubscriptionInfo, error) {
+// topicConsumer opens the topic subscription on every partition of the topic. Events are acknowledged by the acker of
+// their partition, as soon as they are handed to the consumer unless config asks for manual acks.
+func (rm *requestManager) topicConsumer(ctx context.Context, topic, subName string, startPosition int64, config *topicConsumerConfig) (chan *SubscriptionEvent, *zbmsgpack.TopicSubscriptionInfo, error) {
 	partitions, err := rm.topicPartitionsAddrs(topic)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	send := func(subInfo *zbmsgpack.TopicSubscription, endSubscriptionCh chan *SubscriptionEvent, subscriptionCh <-chan *SubscriptionEvent) {
+	var wg sync.WaitGroup
+	send := func(acker *topicAcker, doneCh <-chan struct{}, endSubscriptionCh chan *SubscriptionEvent, subscriptionCh <-chan *SubscriptionEvent) {
+		defer wg.Done()
 		for {
-			msg := <-subscriptionCh
-			endSubscriptionCh <- msg
-			rm.topicSubscriptionAck(subInfo, msg)
+			select {
+			case msg := <-subscriptionCh:
+				msg.acker = acker
+				select {
+				case endSubscriptionCh <- msg:
+				case <-doneCh:
+					return
+				}
+				if !config.manualAck {
+					acker.ack(msg)
+				}
+			case <-doneCh:
+				return
+			}
 		}
 	}
 
 	tsi := zbmsgpack.NewTopicSubscriptionInfo()
 	endSubscriptionCh := make(chan *SubscriptionEvent, 1000)
+	doneCh := rm.addTopicSubscriptionInfo(tsi)
 
 	for partitionID := range *partitions {
 		subscriptionCh := make(chan *SubscriptionEvent, 1000)
 		message := rm.openTopicSubscriptionRequest(partitionID, topic, subName, startPosition)
 		request := newRequestWrapper(message)
-		resp, err := rm.executeRequest(request)
+		resp, err := rm.executeRequest(ctx, request)
 		if err != nil {
+			rm.closeTopicSubscription(context.Background(), tsi)
 			return nil, nil, err
 		}
 
+		if subscriber := rm.unmarshalOpenTopicSubscription(resp); subscriber != nil {
+			if err := checkRejection(TopicSubscriptionSubscribeState, subscriber.State, subscriber); err != nil {
+				rm.closeTopicSubscription(context.Background(), tsi)
+				return nil, nil, err
+			}
+		}
+
 		cmdResponse := (*resp.SbeMessage).(*zbsbe.ExecuteCommandResponse)
 		subscriberKey := cmdResponse.Key
 
@@ -225,16 +488,179 @@ func (rm *requestManager) topicConsumer(topic, subName string, startPosition int
 		}
 
 		tsi.AddSubInfo(subscriptionInfo)
-		go send(&subscriptionInfo, endSubscriptionCh, subscriptionCh)
+		acker := rm.newTopicAcker(&subscriptionInfo, config)
+		go acker.run(doneCh)
+		wg.Add(1)
+		go send(acker, doneCh, endSubscriptionCh, subscriptionCh)
 	}
 
+	go func() {
+		wg.Wait()
+		close(endSubscriptionCh)
+	}()
+
 	return endSubscriptionCh, tsi, nil
 }
 
-func newRequestManager(bootstrapAddr string) *requestManager {
+// incidentConsumer hands the incident events of the topic subscription to the consumer. Events which aren't incidents
+// are acknowledged right away, incidents once they are taken by the consumer unless opts ask for manual acks.
+func (rm *requestManager) incidentConsumer(ctx context.Context, topic, subName string, startPosition int64, opts []TopicConsumerOption) (chan *IncidentEvent, *zbmsgpack.TopicSubscriptionInfo, error) {
+	config, err := newTopicConsumerConfig(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	manualAck := config.manualAck
+	config.manualAck = true
+
+	subscriptionCh, tsi, err := rm.topicConsumer(ctx, topic, subName, startPosition, config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doneCh := rm.topicSubscriptionDone(tsi)
+	incidentCh := make(chan *IncidentEvent)
+	go func() {
+		defer close(incidentCh)
+		for event := range subscriptionCh {
+			incident := event.Incident()
+			if incident == nil {
+				event.Ack()
+				continue
+			}
+
+			select {
+			case incidentCh <- &IncidentEvent{Incident: incident, Event: event.Event, event: event}:
+			case <-doneCh:
+				return
+			}
+			if !manualAck {
+				event.Ack()
+			}
+		}
+	}()
+
+	return incidentCh, tsi, nil
+}
+
+func (rm *requestManager) addTaskSubscriptionInfo(sub *zbmsgpack.TaskSubscriptionInfo) *taskSubscriptionEntry {
+	rm.subscriptionsMu.Lock()
+	defer rm.subscriptionsMu.Unlock()
+
+	entry := &taskSubscriptionEntry{doneCh: make(chan struct{})}
+	rm.taskSubscriptions[sub] = entry
+	return entry
+}
+
+// addTaskSubscriptionPartition will add the opened partition to the subscription and return its index in sub.Subs.
+func (rm *requestManager) addTaskSubscriptionPartition(sub *zbmsgpack.TaskSubscriptionInfo, entry *taskSubscriptionEntry, partition zbmsgpack.TaskSubscription, pc *partitionCredits) int {
+	rm.subscriptionsMu.Lock()
+	defer rm.subscriptionsMu.Unlock()
+
+	sub.AddSubInfo(partition)
+	entry.credits = append(entry.credits, pc)
+	return len(sub.Subs) - 1
+}
+
+// replaceTaskSubscription will replace the partition at index with the reopened one. It returns false if the
+// subscription was closed in the meantime.
+func (rm *requestManager) replaceTaskSubscription(sub *zbmsgpack.TaskSubscriptionInfo, entry *taskSubscriptionEntry, index int, partition zbmsgpack.TaskSubscription) bool {
+	rm.subscriptionsMu.Lock()
+	defer rm.subscriptionsMu.Unlock()
+
+	select {
+	case <-entry.doneCh:
+		return false
+	default:
+	}
+
+	sub.Subs[index] = partition
+	return true
+}
+
+// removeTaskSubscriptionInfo will stop the partitions of the subscription and return them, so that they can be closed.
+func (rm *requestManager) removeTaskSubscriptionInfo(sub *zbmsgpack.TaskSubscriptionInfo) []zbmsgpack.TaskSubscription {
+	rm.subscriptionsMu.Lock()
+	defer rm.subscriptionsMu.Unlock()
+
+	if entry, ok := rm.taskSubscriptions[sub]; ok {
+		close(entry.doneCh)
+		delete(rm.taskSubscriptions, sub)
+	}
+	return append([]zbmsgpack.TaskSubscription(nil), sub.Subs...)
+}
+
+func (rm *requestManager) addTopicSubscriptionInfo(sub *zbmsgpack.TopicSubscriptionInfo) chan struct{} {
+	rm.subscriptionsMu.Lock()
+	defer rm.subscriptionsMu.Unlock()
+
+	doneCh := make(chan struct{})
+	rm.topicSubscriptions[sub] = doneCh
+	return doneCh
+}
+
+// topicSubscriptionDone returns the channel which is closed when the subscription is closed.
+func (rm *requestManager) topicSubscriptionDone(sub *zbmsgpack.TopicSubscriptionInfo) <-chan struct{} {
+	rm.subscriptionsMu.Lock()
+	defer rm.subscriptionsMu.Unlock()
+
+	if doneCh, ok := rm.topicSubscriptions[sub]; ok {
+		return doneCh
+	}
+	doneCh := make(chan struct{})
+	close(doneCh)
+	return doneCh
+}
+
+func (rm *requestManager) removeTopicSubscriptionInfo(sub *zbmsgpack.TopicSubscriptionInfo) {
+	rm.subscriptionsMu.Lock()
+	defer rm.subscriptionsMu.Unlock()
+
+	if doneCh, ok := rm.topicSubscriptions[sub]; ok {
+		close(doneCh)
+		delete(rm.topicSubscriptions, sub)
+	}
+}
+
+// close will close all open subscriptions and afterwards tear down the transport. The first error is returned.
+func (rm *requestManager) close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), rm.config.requestTimeout)
+	defer cancel()
+
+	rm.subscriptionsMu.Lock()
+	taskSubs := make([]*zbmsgpack.TaskSubscriptionInfo, 0, len(rm.taskSubscriptions))
+	for sub := range rm.taskSubscriptions {
+		taskSubs = append(taskSubs, sub)
+	}
+	topicSubs := make([]*zbmsgpack.TopicSubscriptionInfo, 0, len(rm.topicSubscriptions))
+	for sub := range rm.topicSubscriptions {
+		topicSubs = append(topicSubs, sub)
+	}
+	rm.subscriptionsMu.Unlock()
+
+	var err error
+	for _, sub := range taskSubs {
+		if errs := rm.closeTaskSubscription(ctx, sub); len(errs) > 0 && err == nil {
+			err = errs[0]
+		}
+	}
+	for _, sub := range topicSubs {
+		if errs := rm.closeTopicSubscription(ctx, sub); len(errs) > 0 && err == nil {
+			err = errs[0]
+		}
+	}
+
+	rm.closeTransport()
+	return err
+}
+
+func newRequestManager(config *clientConfig) *requestManager {
 	return &requestManager{
-		newRequestFactory(),
-		newResponseHandler(),
-		newTopologyManager(bootstrapAddr),
+		requestFactory:     newRequestFactory(),
+		responseHandler:    newResponseHandler(),
+		topologyManager:    newTopologyManager(config.bootstrapAddrs, config),
+		taskSubscriptions:  make(map[*zbmsgpack.TaskSubscriptionInfo]*taskSubscriptionEntry),
+		topicSubscriptions: make(map[*zbmsgpack.TopicSubscriptionInfo]chan struct{}),
+		taskLimits:         newRateLimits(config.taskRateLimits),
+		commandLimits:      newRateLimits(config.commandRateLimits),
 	}
 }
@@ -0,0 +1,61 @@
+package zbc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTaskWithPayloadLeavesEventUntouched(t *testing.T) {
+	event := newTaskEvent(3)
+	if err := event.Task.SetPayload(map[string]interface{}{"a": "b", "nested": map[string]interface{}{"c": 1}}); err != nil {
+		t.Fatalf("SetPayload failed: %s", err)
+	}
+	original := append([]byte(nil), event.Task.Payload...)
+
+	for _, merge := range []bool{false, true} {
+		completed, err := taskWithPayload(event, map[string]interface{}{"nested": map[string]interface{}{"d": 2}}, merge)
+		if err != nil {
+			t.Fatalf("taskWithPayload(merge=%v) failed: %s", merge, err)
+		}
+
+		var document map[string]interface{}
+		if err := completed.Task.UnmarshalPayload(&document); err != nil {
+			t.Fatalf("UnmarshalPayload failed: %s", err)
+		}
+		nested := document["nested"].(map[string]interface{})
+		if _, ok := document["a"]; ok != merge {
+			t.Fatalf("merge=%v: unexpected payload %v", merge, document)
+		}
+		if _, ok := nested["c"]; ok != merge {
+			t.Fatalf("merge=%v: unexpected payload %v", merge, document)
+		}
+		if completed.Event != event.Event {
+			t.Fatal("copy doesn't address the event of the task")
+		}
+	}
+
+	if _, err := taskWithPayload(event, 42, false); err == nil {
+		t.Fatal("expected error for scalar payload")
+	}
+	if !bytes.Equal(event.Task.Payload, original) {
+		t.Fatalf("payload of the event changed to %v", event.Task.Payload)
+	}
+}
+
+func TestCompleteTaskLockExpired(t *testing.T) {
+	event := lockedTaskEvent(time.Now().Add(-time.Second), time.Second)
+
+	// Expired tasks are rejected before any request is sent, so no transport is needed.
+	rm := &requestManager{}
+	if _, err := rm.completeTask(context.Background(), event); err != ErrTaskLockExpired {
+		t.Fatalf("expected ErrTaskLockExpired, got %v", err)
+	}
+	if _, err := rm.completeTaskWithPayload(context.Background(), event, map[string]interface{}{"a": "b"}, false); err != ErrTaskLockExpired {
+		t.Fatalf("expected ErrTaskLockExpired, got %v", err)
+	}
+	if event.Task.Payload != nil {
+		t.Fatalf("payload of the event changed to %v", event.Task.Payload)
+	}
+}
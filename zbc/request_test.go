@@ -0,0 +1,65 @@
+package zbc
+
+import (
+	"testing"
+
+	"github.com/vmihailenco/msgpack"
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+	"github.com/zeebe-io/zbc-go/zbc/zbsbe"
+)
+
+func newTaskEvent(retries int) *SubscriptionEvent {
+	task := NewTask("foo", "owner")
+	task.State = TaskCreated
+	task.Retries = retries
+	return &SubscriptionEvent{
+		Task:  task,
+		Event: &zbsbe.SubscribedEvent{PartitionId: 1, Position: 2, Key: 3, SubscriberKey: 4},
+	}
+}
+
+func commandTask(t *testing.T, message *Message) *zbmsgpack.Task {
+	request, ok := (*message.SbeMessage).(*zbsbe.ExecuteCommandRequest)
+	if !ok {
+		t.Fatalf("unexpected request %T", *message.SbeMessage)
+	}
+	if request.PartitionId != 1 || request.Position != 2 || request.Key != 3 {
+		t.Fatalf("command not addressed to the task: %+v", request)
+	}
+
+	var task zbmsgpack.Task
+	if err := msgpack.Unmarshal(request.Command, &task); err != nil {
+		t.Fatalf("decoding command failed: %s", err)
+	}
+	return &task
+}
+
+func TestTaskCommandRequestsLeaveEventUntouched(t *testing.T) {
+	rf := newRequestFactory()
+	event := newTaskEvent(3)
+
+	tests := []struct {
+		name    string
+		message func() *Message
+		state   string
+		retries int
+	}{
+		{"complete", func() *Message { return rf.completeTaskRequest(event) }, TaskComplete, 3},
+		{"fail", func() *Message { return rf.failTaskRequest(event) }, TaskFail, 2},
+		{"update retries", func() *Message { return rf.updateTaskRetriesRequest(event, 5) }, TaskUpdateRetries, 5},
+		{"cancel", func() *Message { return rf.cancelTaskRequest(event) }, TaskCancel, 3},
+	}
+
+	for _, test := range tests {
+		// Sending the command again, for example after a transport error, must send the same command.
+		for i := 0; i < 2; i++ {
+			task := commandTask(t, test.message())
+			if task.State != test.state || task.Retries != test.retries {
+				t.Fatalf("%s: unexpected command state=%s retries=%d", test.name, task.State, task.Retries)
+			}
+		}
+		if event.Task.State != TaskCreated || event.Task.Retries != 3 {
+			t.Fatalf("%s: event changed to state=%s retries=%d", test.name, event.Task.State, event.Task.Retries)
+		}
+	}
+}
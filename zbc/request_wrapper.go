@@ -1,19 +1,53 @@
 package zbc
 
+import (
+	"context"
+	"sync"
+)
+
 type requestWrapper struct {
-	addr string
+	sync.Mutex
+
+	ctx        context.Context
+	addr       string
 	sock       *socket
 	responseCh chan *Message
 	errorCh    chan error
 	payload    *Message
 }
 
+func (rw *requestWrapper) setSocket(sock *socket) {
+	rw.Lock()
+	rw.sock = sock
+	rw.Unlock()
+}
+
+func (rw *requestWrapper) socket() *socket {
+	rw.Lock()
+	defer rw.Unlock()
+	return rw.sock
+}
+
+// fail will hand the error to the caller unless the request was already failed.
+func (rw *requestWrapper) fail(err error) {
+	select {
+	case rw.errorCh <- err:
+	default:
+	}
+}
+
+// release will free the slot which request holds in the dispatcher of its socket.
+func (rw *requestWrapper) release() {
+	if sock := rw.socket(); sock != nil {
+		sock.removeTransaction(rw)
+	}
+}
+
 func newRequestWrapper(payload *Message) *requestWrapper {
 	return &requestWrapper{
-		"",
-		nil,
-		make(chan *Message),
-		make(chan error),
-		payload,
+		ctx:        context.Background(),
+		responseCh: make(chan *Message, 1),
+		errorCh:    make(chan error, 1),
+		payload:    payload,
 	}
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/vmihailenco/msgpack"
 	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+	"github.com/zeebe-io/zbc-go/zbc/zbsbe"
 )
 
 type responseHandler struct{}
@@ -89,7 +90,7 @@ func (rf *responseHandler) unmarshalWorkflowInstance(m *Message) *zbmsgpack.Work
 	if err != nil {
 		return nil
 	}
-	if len(d.BPMNProcessID) > 0 {
+	if len(d.State) > 0 {
 		return &d
 	}
 	return nil
@@ -101,12 +102,66 @@ func (rf *responseHandler) unmarshalTopicSubAck(m *Message) *zbmsgpack.TopicSubs
 	if err != nil {
 		return nil
 	}
-	if d.State == TopicSubscriptionAcknowledgedState {
+	if len(d.State) > 0 {
+		return &d
+	}
+	return nil
+}
+
+func (rf *responseHandler) unmarshalOpenTopicSubscription(m *Message) *zbmsgpack.OpenTopicSubscription {
+	var d zbmsgpack.OpenTopicSubscription
+	err := msgpack.Unmarshal(m.Data, &d)
+	if err != nil {
+		return nil
+	}
+	return &d
+}
+
+func (rf *responseHandler) unmarshalIncident(m *Message) *zbmsgpack.Incident {
+	var d zbmsgpack.Incident
+	err := msgpack.Unmarshal(m.Data, &d)
+	if err != nil {
+		return nil
+	}
+	if len(d.State) > 0 {
 		return &d
 	}
 	return nil
 }
 
+// unmarshalSubscribedEvent will decode the event into the message pack structure which matches its EventType. It returns
+// nil if the event type is unknown or the event can't be decoded.
+func (rf *responseHandler) unmarshalSubscribedEvent(event *zbsbe.SubscribedEvent) interface{} {
+	var value interface{}
+	switch event.EventType {
+	case zbsbe.EventType.TASK_EVENT:
+		value = &zbmsgpack.Task{}
+	case zbsbe.EventType.RAFT_EVENT:
+		value = &zbmsgpack.Raft{}
+	case zbsbe.EventType.SUBSCRIPTION_EVENT:
+		value = &zbmsgpack.TopicSubscriptionAck{}
+	case zbsbe.EventType.SUBSCRIBER_EVENT:
+		value = &zbmsgpack.OpenTopicSubscription{}
+	case zbsbe.EventType.DEPLOYMENT_EVENT:
+		value = &zbmsgpack.Workflow{}
+	case zbsbe.EventType.WORKFLOW_INSTANCE_EVENT:
+		value = &zbmsgpack.WorkflowInstance{}
+	case zbsbe.EventType.INCIDENT_EVENT:
+		value = &zbmsgpack.Incident{}
+	case zbsbe.EventType.WORKFLOW_EVENT:
+		value = &zbmsgpack.DeployedWorkflow{}
+	case zbsbe.EventType.TOPIC_EVENT:
+		value = &zbmsgpack.Topic{}
+	default:
+		return nil
+	}
+
+	if err := msgpack.Unmarshal(event.Event, value); err != nil {
+		return nil
+	}
+	return value
+}
+
 func newResponseHandler() *responseHandler {
 	return &responseHandler{}
 }
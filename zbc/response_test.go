@@ -0,0 +1,120 @@
+package zbc
+
+import (
+	"testing"
+
+	"github.com/vmihailenco/msgpack"
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+	"github.com/zeebe-io/zbc-go/zbc/zbsbe"
+)
+
+func encodeFixture(t *testing.T, v interface{}) []byte {
+	b, err := msgpack.Marshal(v)
+	if err != nil {
+		t.Fatalf("encoding fixture failed: %s", err)
+	}
+	return b
+}
+
+// typedStates returns the state of every typed value the accessors of the event return.
+func typedStates(se *SubscriptionEvent) []string {
+	var states []string
+	if v := se.WorkflowInstance(); v != nil {
+		states = append(states, v.State)
+	}
+	if v := se.Deployment(); v != nil {
+		states = append(states, v.State)
+	}
+	if v := se.Workflow(); v != nil {
+		states = append(states, v.State)
+	}
+	if v := se.Incident(); v != nil {
+		states = append(states, v.State)
+	}
+	if v := se.Topic(); v != nil {
+		states = append(states, v.State)
+	}
+	if v := se.Raft(); v != nil {
+		states = append(states, v.State)
+	}
+	if v := se.Subscriber(); v != nil {
+		states = append(states, v.State)
+	}
+	if v := se.Subscription(); v != nil {
+		states = append(states, v.State)
+	}
+	return states
+}
+
+func TestUnmarshalSubscribedEvent(t *testing.T) {
+	rh := newResponseHandler()
+
+	tests := []struct {
+		name      string
+		eventType zbsbe.EventTypeEnum
+		fixture   map[string]interface{}
+	}{
+		{"workflow instance", zbsbe.EventType.WORKFLOW_INSTANCE_EVENT, map[string]interface{}{"state": WorkflowInstanceCreated, "bpmnProcessId": "order", "workflowInstanceKey": 4}},
+		{"deployment", zbsbe.EventType.DEPLOYMENT_EVENT, map[string]interface{}{"state": DeploymentCreated, "topicName": "default-topic"}},
+		{"workflow", zbsbe.EventType.WORKFLOW_EVENT, map[string]interface{}{"state": "CREATED", "bpmnProcessId": "order", "version": 2}},
+		{"incident", zbsbe.EventType.INCIDENT_EVENT, map[string]interface{}{"state": IncidentCreated, "errorType": "IO_MAPPING_ERROR"}},
+		{"topic", zbsbe.EventType.TOPIC_EVENT, map[string]interface{}{"state": "CREATED", "name": "orders", "partitions": 3}},
+		{"raft", zbsbe.EventType.RAFT_EVENT, map[string]interface{}{"state": "MEMBER_ADDED", "members": []map[string]interface{}{{"host": "localhost", "port": 51017}}}},
+		{"subscriber", zbsbe.EventType.SUBSCRIBER_EVENT, map[string]interface{}{"state": "SUBSCRIBED", "name": "sub", "prefetchCapacity": 32}},
+		{"subscription", zbsbe.EventType.SUBSCRIPTION_EVENT, map[string]interface{}{"state": "ACKNOWLEDGED", "name": "sub", "ackPosition": 12}},
+	}
+
+	for _, test := range tests {
+		event := &zbsbe.SubscribedEvent{EventType: test.eventType, Event: encodeFixture(t, test.fixture)}
+		se := &SubscriptionEvent{Event: event, Value: rh.unmarshalSubscribedEvent(event)}
+
+		// Exactly one accessor matches the event type, all the others return nil.
+		states := typedStates(se)
+		if len(states) != 1 || states[0] != test.fixture["state"] {
+			t.Fatalf("%s: expected state %v from one accessor, got %v", test.name, test.fixture["state"], states)
+		}
+	}
+}
+
+func TestUnmarshalSubscribedTaskEvent(t *testing.T) {
+	rh := newResponseHandler()
+	fixture := map[string]interface{}{"state": TaskCreated, "type": "foo", "retries": 3, "headers": map[string]interface{}{"a": "b"}}
+	event := &zbsbe.SubscribedEvent{EventType: zbsbe.EventType.TASK_EVENT, Event: encodeFixture(t, fixture)}
+
+	task, ok := rh.unmarshalSubscribedEvent(event).(*zbmsgpack.Task)
+	if !ok {
+		t.Fatal("task event wasn't decoded into a task")
+	}
+	if task.State != TaskCreated || task.Type != "foo" || task.Retries != 3 || task.Headers["a"] != "b" {
+		t.Fatalf("unexpected task %+v", task)
+	}
+}
+
+func TestUnmarshalSubscribedEventUndecodable(t *testing.T) {
+	rh := newResponseHandler()
+
+	tests := []struct {
+		name      string
+		eventType zbsbe.EventTypeEnum
+		event     []byte
+	}{
+		{"unknown type", zbsbe.EventTypeEnum(42), encodeFixture(t, map[string]interface{}{"state": "CREATED"})},
+		{"noop", zbsbe.EventType.NOOP_EVENT, encodeFixture(t, map[string]interface{}{"state": "CREATED"})},
+		{"null type", zbsbe.EventType.NullValue, encodeFixture(t, map[string]interface{}{"state": "CREATED"})},
+		{"no document", zbsbe.EventType.TASK_EVENT, encodeFixture(t, "CREATED")},
+		{"mismatched field", zbsbe.EventType.TASK_EVENT, encodeFixture(t, map[string]interface{}{"retries": "many"})},
+		{"mismatched event", zbsbe.EventType.TOPIC_EVENT, encodeFixture(t, map[string]interface{}{"partitions": []string{"a"}})},
+		{"truncated", zbsbe.EventType.INCIDENT_EVENT, encodeFixture(t, map[string]interface{}{"state": IncidentCreated})[:3]},
+	}
+
+	for _, test := range tests {
+		event := &zbsbe.SubscribedEvent{EventType: test.eventType, Event: test.event}
+		se := &SubscriptionEvent{Event: event, Value: rh.unmarshalSubscribedEvent(event)}
+		if se.Value != nil {
+			t.Fatalf("%s: expected no value, got %+v", test.name, se.Value)
+		}
+		if states := typedStates(se); len(states) != 0 {
+			t.Fatalf("%s: expected no accessor to match, got %v", test.name, states)
+		}
+	}
+}
@@ -77,9 +77,13 @@ type Operation func() (*Message, error)
 
 // MessageRetry will try to execute operation and handle retrying under specified deadline.
 func MessageRetry(op Operation) (*Message, error) {
+	return messageRetry(op, BackoffMin, BackoffMax, BackoffDeadline)
+}
+
+func messageRetry(op Operation, min, max, deadline time.Duration) (*Message, error) {
 	b := &backoff{
-		Min:    BackoffMin,
-		Max:    BackoffMax,
+		Min:    min,
+		Max:    max,
 		Factor: 2,
 		Jitter: true,
 	}
@@ -87,11 +91,11 @@ func MessageRetry(op Operation) (*Message, error) {
 	start := time.Now()
 	for {
 		msg, err := op()
-		if err != nil && time.Since(start) < BackoffDeadline {
+		if err != nil && time.Since(start) < deadline {
 			time.Sleep(b.Duration())
 			continue
 		}
-		if time.Since(start) > BackoffDeadline {
+		if time.Since(start) > deadline {
 			return nil, RetryDeadlineReached
 		}
 
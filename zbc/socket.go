@@ -2,17 +2,26 @@ package zbc
 
 import (
 	"bytes"
-	"github.com/zeebe-io/zbc-go/zbc/zbsbe"
+	"errors"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+	"github.com/zeebe-io/zbc-go/zbc/zbsbe"
 )
 
+var errSocketClosed = errors.New("socket closed")
+
 type socket struct {
-	dispatcher
+	*dispatcher
 
 	connection net.Conn
 	stream     []byte
-	closeCh    chan bool
+	chunkSize  int
+	closeCh    chan struct{}
+	closeOnce  sync.Once
 }
 
 func (s *socket) sender(message *Message) error {
@@ -35,15 +44,20 @@ func (s *socket) receiver() {
 	reader := NewMessageReader(s)
 	responseHandler := responseHandler{}
 
+	// Requests which are still waiting for the response will never get it.
+	defer s.failTransactions(errSocketClosed)
+
 	for {
 		select {
 		case <-s.closeCh:
-			s.connection.Close()
 			return
 
 		default:
 
 			headers, tail, err := reader.readHeaders()
+			if err == errSocketClosed {
+				return
+			}
 			if err != nil {
 				continue
 			}
@@ -54,7 +68,7 @@ func (s *socket) receiver() {
 				continue
 			}
 
-			if !headers.IsSingleMessage() && message != nil && len(message.Data) > 0 {
+			if !headers.IsSingleMessage() && message != nil && (len(message.Data) > 0 || message.brokerError() != nil) {
 				s.dispatchTransaction(headers.RequestResponseHeader.RequestID, message)
 				continue
 			}
@@ -65,59 +79,90 @@ func (s *socket) receiver() {
 
 			if headers.IsSingleMessage() && message != nil && len(message.Data) > 0 {
 				event := (*message.SbeMessage).(*zbsbe.SubscribedEvent)
-				if task := responseHandler.unmarshalTask(message); task != nil {
+				value := responseHandler.unmarshalSubscribedEvent(event)
+				switch event.SubscriptionType {
+				case zbsbe.SubscriptionType.TASK_SUBSCRIPTION:
+					task, _ := value.(*zbmsgpack.Task)
 					s.dispatchTaskEvent(event.SubscriberKey, event, task)
-				} else {
-					s.dispatchTopicEvent(event.SubscriberKey, event)
+				case zbsbe.SubscriptionType.TOPIC_SUBSCRIPTION:
+					s.dispatchTopicEvent(event.SubscriberKey, event, value)
+				default:
+					atomic.AddUint64(s.unknownSubscriberEvents, 1)
 				}
 			}
 		}
 	}
 }
 
+// teardown will stop the receiver and close the connection. It is safe to call it multiple times.
 func (s *socket) teardown() {
-	close(s.closeCh)
+	s.closeOnce.Do(func() {
+		close(s.closeCh)
+		s.connection.Close()
+	})
 }
 
-func (s *socket) readChunk() {
+func (s *socket) isClosed() bool {
+	select {
+	case <-s.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *socket) readChunk() error {
 	for {
+		select {
+		case <-s.closeCh:
+			return errSocketClosed
+		default:
+		}
+
 		s.connection.SetReadDeadline(time.Now().Add(time.Millisecond * 100))
 
-		total := make([]byte, SocketChunkSize)
+		total := make([]byte, s.chunkSize)
 		returnedSize, err := s.connection.Read(total)
-		if err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
 			continue
 		}
+		if err != nil {
+			// Connection is lost, socket can't be used anymore.
+			s.teardown()
+			return errSocketClosed
+		}
 
 		s.stream = append(s.stream, total[:returnedSize]...)
-		break
+		return nil
 	}
 }
 
-func (s *socket) getBytes(start, end int) []byte {
+func (s *socket) getBytes(start, end int) ([]byte, error) {
 	for {
 		if end-start > len(s.stream) || end > len(s.stream) {
-			s.readChunk()
+			if err := s.readChunk(); err != nil {
+				return nil, err
+			}
 		} else {
 			break
 		}
 	}
 
 	frame := s.stream[start:end]
-	return frame
+	return frame, nil
 }
 
 func (s *socket) popBytes(pos int) {
 	s.stream = s.stream[pos:]
 }
 
-func (s *socket) dial(addr string) error {
+func (s *socket) dial(addr string, timeout time.Duration) error {
 	tcpAddr, wrongAddr := net.ResolveTCPAddr("tcp4", addr)
 	if wrongAddr != nil {
 		return wrongAddr
 	}
 
-	conn, err := net.DialTCP("tcp", nil, tcpAddr)
+	conn, err := net.DialTimeout("tcp", tcpAddr.String(), timeout)
 	if err != nil {
 		return err
 	}
@@ -126,15 +171,17 @@ func (s *socket) dial(addr string) error {
 	return nil
 }
 
-func newSocketStream(addr string) *socket {
+func newSocketStream(addr string, chunkSize int, dialTimeout time.Duration, unknownSubscriberEvents *uint64) *socket {
+	closeCh := make(chan struct{})
 	ss := &socket{
-		newDispatcher(),
-		nil,
-		make([]byte, 0),
-		make(chan bool),
+		dispatcher: newDispatcher(closeCh, unknownSubscriberEvents),
+		connection: nil,
+		stream:     make([]byte, 0),
+		chunkSize:  chunkSize,
+		closeCh:    closeCh,
 	}
 
-	err := ss.dial(addr)
+	err := ss.dial(addr, dialTimeout)
 	if err != nil {
 		return nil
 	}
@@ -0,0 +1,116 @@
+package zbc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack"
+	"github.com/zeebe-io/zbc-go/zbc/zbprotocol"
+	"github.com/zeebe-io/zbc-go/zbc/zbsbe"
+)
+
+// subscribedEventFrame encodes the event the way the broker pushes it to a subscription.
+func subscribedEventFrame(t *testing.T, event *zbsbe.SubscribedEvent) []byte {
+	var body bytes.Buffer
+	if err := event.Encode(&body, binary.LittleEndian, false); err != nil {
+		t.Fatalf("encoding event failed: %s", err)
+	}
+
+	var headers Headers
+	headers.SetFrameHeader(zbprotocol.NewFrameHeader(uint32(FrameHeaderSize+TransportHeaderSize+SBEMessageHeaderSize+body.Len()), 0, 0, 0, 0))
+	headers.SetTransportHeader(zbprotocol.NewTransportHeader(zbprotocol.FullDuplexSingleMessage))
+	headers.SetSbeMessageHeader(&zbsbe.MessageHeader{
+		BlockLength: event.SbeBlockLength(),
+		TemplateId:  event.SbeTemplateId(),
+		SchemaId:    event.SbeSchemaId(),
+		Version:     event.SbeSchemaVersion(),
+	})
+
+	var msg Message
+	msg.SetHeaders(&headers)
+	msg.SetSbeMessage(event)
+
+	var frame bytes.Buffer
+	NewMessageWriter(&msg).Write(&frame)
+	return frame.Bytes()
+}
+
+func newSubscribedEvent(t *testing.T, subscriptionType zbsbe.SubscriptionTypeEnum, eventType zbsbe.EventTypeEnum, subscriberKey uint64, state string) *zbsbe.SubscribedEvent {
+	b, err := msgpack.Marshal(map[string]interface{}{"state": state})
+	if err != nil {
+		t.Fatalf("encoding event failed: %s", err)
+	}
+	return &zbsbe.SubscribedEvent{
+		PartitionId:      1,
+		SubscriberKey:    subscriberKey,
+		SubscriptionType: subscriptionType,
+		EventType:        eventType,
+		Event:            b,
+	}
+}
+
+func TestReceiverRoutesBySubscriptionType(t *testing.T) {
+	client, broker := net.Pipe()
+	defer broker.Close()
+
+	var unknownSubscriberEvents uint64
+	closeCh := make(chan struct{})
+	s := &socket{
+		dispatcher: newDispatcher(closeCh, &unknownSubscriberEvents),
+		connection: client,
+		stream:     make([]byte, 0),
+		chunkSize:  SocketChunkSize,
+		closeCh:    closeCh,
+	}
+	defer s.teardown()
+
+	// Task and topic subscriptions have separate key spaces, so both may use the same subscriber key.
+	const subscriberKey = 7
+	taskCh := make(chan *SubscriptionEvent, 10)
+	topicCh := make(chan *SubscriptionEvent, 10)
+	s.addTaskSubscription(subscriberKey, taskCh)
+	s.addTopicSubscription(subscriberKey, topicCh)
+	go s.receiver()
+
+	events := []*zbsbe.SubscribedEvent{
+		newSubscribedEvent(t, zbsbe.SubscriptionType.TASK_SUBSCRIPTION, zbsbe.EventType.TASK_EVENT, subscriberKey+1, "LOCKED"),
+		newSubscribedEvent(t, zbsbe.SubscriptionType.TOPIC_SUBSCRIPTION, zbsbe.EventType.TASK_EVENT, subscriberKey+1, TaskCreated),
+		newSubscribedEvent(t, zbsbe.SubscriptionType.NullValue, zbsbe.EventType.TASK_EVENT, subscriberKey, TaskCreated),
+		newSubscribedEvent(t, zbsbe.SubscriptionType.TASK_SUBSCRIPTION, zbsbe.EventType.TASK_EVENT, subscriberKey, "LOCKED"),
+		newSubscribedEvent(t, zbsbe.SubscriptionType.TOPIC_SUBSCRIPTION, zbsbe.EventType.TASK_EVENT, subscriberKey, TaskCreated),
+	}
+	go func() {
+		for _, event := range events {
+			broker.Write(subscribedEventFrame(t, event))
+		}
+	}()
+
+	var task, topic *SubscriptionEvent
+	for task == nil || topic == nil {
+		select {
+		case task = <-taskCh:
+		case topic = <-topicCh:
+		case <-time.After(5 * time.Second):
+			t.Fatal("events weren't dispatched")
+		}
+	}
+
+	if task.Task == nil || task.Task.State != "LOCKED" || task.Event.SubscriptionType != zbsbe.SubscriptionType.TASK_SUBSCRIPTION {
+		t.Fatalf("unexpected event on the task subscription: %+v", task)
+	}
+	if topic.Task == nil || topic.Task.State != TaskCreated || topic.Event.SubscriptionType != zbsbe.SubscriptionType.TOPIC_SUBSCRIPTION {
+		t.Fatalf("unexpected event on the topic subscription: %+v", topic)
+	}
+	if len(taskCh) != 0 || len(topicCh) != 0 {
+		t.Fatalf("unexpected events: %d on the task subscription, %d on the topic subscription", len(taskCh), len(topicCh))
+	}
+
+	// Events are dispatched in order, so the unknown ones were counted before the known ones arrived.
+	if n := atomic.LoadUint64(&unknownSubscriberEvents); n != 3 {
+		t.Fatalf("expected 3 unknown subscriber events, got %d", n)
+	}
+}
@@ -18,10 +18,6 @@ func (sm *subscriptionsManager) addTopicSubscription(key uint64, value interface
 }
 
 func (sm *subscriptionsManager) removeTaskSubscription(key uint64) {
-	if ch, ok := sm.taskSubscriptions.Get(fmt.Sprintf("%d", key)); ok {
-		c := ch.(chan *SubscriptionEvent)
-		close(c)
-	}
 	sm.taskSubscriptions.Remove(fmt.Sprintf("%d", key))
 }
 
@@ -0,0 +1,93 @@
+package zbc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+)
+
+// TaskMiddleware wraps a TaskHandler to add behaviour around the handling of a task.
+type TaskMiddleware func(TaskHandler) TaskHandler
+
+// ChainTaskHandler wraps handler with the middlewares. The first middleware is the outermost one, so it sees the task
+// first and the result last.
+func ChainTaskHandler(handler TaskHandler, middlewares ...TaskMiddleware) TaskHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// PanicError is returned by the handler wrapped with RecoveryMiddleware when it panics.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task handler panicked: %v", e.Value)
+}
+
+// RecoveryMiddleware recovers from a panic of the handler and returns it as *PanicError, so that the task is failed
+// instead of crashing the process.
+func RecoveryMiddleware() TaskMiddleware {
+	return func(next TaskHandler) TaskHandler {
+		return TaskHandlerFunc(func(ctx context.Context, task *zbmsgpack.Task) (payload interface{}, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					payload, err = nil, &PanicError{Value: r, Stack: debug.Stack()}
+				}
+			}()
+			return next.Handle(ctx, task)
+		})
+	}
+}
+
+// LoggingMiddleware writes a line with the type, retries, duration and outcome of every handled task to logger.
+func LoggingMiddleware(logger *log.Logger) TaskMiddleware {
+	return func(next TaskHandler) TaskHandler {
+		return TaskHandlerFunc(func(ctx context.Context, task *zbmsgpack.Task) (interface{}, error) {
+			start := time.Now()
+			payload, err := next.Handle(ctx, task)
+			if err != nil {
+				logger.Printf("task handled type=%q lockOwner=%q retries=%d duration=%s error=%q", task.Type, task.LockOwner, task.Retries, time.Since(start), err)
+			} else {
+				logger.Printf("task handled type=%q lockOwner=%q retries=%d duration=%s", task.Type, task.LockOwner, task.Retries, time.Since(start))
+			}
+			return payload, err
+		})
+	}
+}
+
+// TimingMiddleware calls observe with the time the handler took for every handled task.
+func TimingMiddleware(observe func(task *zbmsgpack.Task, duration time.Duration, err error)) TaskMiddleware {
+	return func(next TaskHandler) TaskHandler {
+		return TaskHandlerFunc(func(ctx context.Context, task *zbmsgpack.Task) (interface{}, error) {
+			start := time.Now()
+			payload, err := next.Handle(ctx, task)
+			observe(task, time.Since(start), err)
+			return payload, err
+		})
+	}
+}
+
+// ValidationMiddleware calls validate with the decoded payload of the task before it is handled. When validate returns
+// an error the handler isn't called and the task is failed with that error.
+func ValidationMiddleware(validate func(payload map[string]interface{}) error) TaskMiddleware {
+	return func(next TaskHandler) TaskHandler {
+		return TaskHandlerFunc(func(ctx context.Context, task *zbmsgpack.Task) (interface{}, error) {
+			var payload map[string]interface{}
+			if err := task.UnmarshalPayload(&payload); err != nil {
+				return nil, err
+			}
+			if err := validate(payload); err != nil {
+				return nil, err
+			}
+			return next.Handle(ctx, task)
+		})
+	}
+}
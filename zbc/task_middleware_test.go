@@ -0,0 +1,125 @@
+package zbc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+)
+
+// recordingMiddleware appends its name to calls before and after the handler.
+func recordingMiddleware(name string, calls *[]string) TaskMiddleware {
+	return func(next TaskHandler) TaskHandler {
+		return TaskHandlerFunc(func(ctx context.Context, task *zbmsgpack.Task) (interface{}, error) {
+			*calls = append(*calls, name+" before")
+			payload, err := next.Handle(ctx, task)
+			*calls = append(*calls, name+" after")
+			return payload, err
+		})
+	}
+}
+
+func TestChainTaskHandlerOrder(t *testing.T) {
+	var calls []string
+	handler := TaskHandlerFunc(func(ctx context.Context, task *zbmsgpack.Task) (interface{}, error) {
+		calls = append(calls, "handler")
+		return nil, nil
+	})
+
+	chained := ChainTaskHandler(handler, recordingMiddleware("first", &calls), recordingMiddleware("second", &calls))
+	chained.Handle(context.Background(), NewTask("foo", "owner"))
+
+	expected := "first before,second before,handler,second after,first after"
+	if got := strings.Join(calls, ","); got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestRecoveryMiddlewareFailsTask(t *testing.T) {
+	handler := TaskHandlerFunc(func(ctx context.Context, task *zbmsgpack.Task) (interface{}, error) {
+		panic("boom")
+	})
+
+	event := newTaskEvent(3)
+	_, err := ChainTaskHandler(handler, RecoveryMiddleware()).Handle(context.Background(), event.Task)
+
+	panicErr, ok := err.(*PanicError)
+	if !ok {
+		t.Fatalf("expected *PanicError, got %T %v", err, err)
+	}
+	if panicErr.Value != "boom" || !bytes.Contains(panicErr.Stack, []byte("TestRecoveryMiddlewareFailsTask")) {
+		t.Fatalf("unexpected panic error %v with stack %s", panicErr.Value, panicErr.Stack)
+	}
+
+	// Worker fails the task with the error of the handler, its message ends up in the custom headers.
+	task := commandTask(t, newRequestFactory().failTaskRequest(taskWithError(event, err)))
+	if task.State != TaskFail || task.CustomHeader[TaskErrorMessageHeader] != "task handler panicked: boom" {
+		t.Fatalf("unexpected fail command %+v", task)
+	}
+	if _, ok := event.Task.CustomHeader[TaskErrorMessageHeader]; ok {
+		t.Fatal("custom headers of the event changed")
+	}
+}
+
+func TestRecoveryMiddlewarePassesResult(t *testing.T) {
+	handler := TaskHandlerFunc(func(ctx context.Context, task *zbmsgpack.Task) (interface{}, error) {
+		return "payload", nil
+	})
+
+	payload, err := ChainTaskHandler(handler, RecoveryMiddleware()).Handle(context.Background(), NewTask("foo", "owner"))
+	if payload != "payload" || err != nil {
+		t.Fatalf("unexpected result %v, %v", payload, err)
+	}
+}
+
+func TestValidationMiddleware(t *testing.T) {
+	errMissingOrder := errors.New("orderId is required")
+	validate := func(payload map[string]interface{}) error {
+		if _, ok := payload["orderId"]; !ok {
+			return errMissingOrder
+		}
+		return nil
+	}
+
+	called := 0
+	handler := ChainTaskHandler(TaskHandlerFunc(func(ctx context.Context, task *zbmsgpack.Task) (interface{}, error) {
+		called++
+		return nil, nil
+	}), ValidationMiddleware(validate))
+
+	invalid := NewTask("foo", "owner")
+	invalid.SetPayload(map[string]interface{}{"amount": 1})
+	if _, err := handler.Handle(context.Background(), invalid); err != errMissingOrder || called != 0 {
+		t.Fatalf("expected validation error without handling, got %v after %d calls", err, called)
+	}
+
+	valid := NewTask("foo", "owner")
+	valid.SetPayload(map[string]interface{}{"orderId": "o-1"})
+	if _, err := handler.Handle(context.Background(), valid); err != nil || called != 1 {
+		t.Fatalf("expected task to be handled, got %v after %d calls", err, called)
+	}
+}
+
+func TestLoggingAndTimingMiddleware(t *testing.T) {
+	var out bytes.Buffer
+	var observed time.Duration
+	handler := ChainTaskHandler(TaskHandlerFunc(func(ctx context.Context, task *zbmsgpack.Task) (interface{}, error) {
+		time.Sleep(time.Millisecond)
+		return nil, errors.New("kaputt")
+	}), LoggingMiddleware(log.New(&out, "", 0)), TimingMiddleware(func(task *zbmsgpack.Task, duration time.Duration, err error) {
+		observed = duration
+	}))
+
+	handler.Handle(context.Background(), NewTask("foo", "owner"))
+	if !strings.Contains(out.String(), `type="foo"`) || !strings.Contains(out.String(), `error="kaputt"`) {
+		t.Fatalf("unexpected log line %q", out.String())
+	}
+	if observed < time.Millisecond {
+		t.Fatalf("unexpected duration %s", observed)
+	}
+}
@@ -0,0 +1,226 @@
+package zbc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+)
+
+var (
+	errRouterStarted    = errors.New("task router already started")
+	errRouterNotStarted = errors.New("task router not started")
+	errNoRoutes         = errors.New("task router has no handlers")
+)
+
+type taskRouteKey struct {
+	topic    string
+	taskType string
+}
+
+type taskRoute struct {
+	taskRouteKey
+	handler      TaskHandler
+	subscription *zbmsgpack.TaskSubscriptionInfo
+}
+
+// routedTask is a task on its way from the subscription to the handler of its route.
+type routedTask struct {
+	event   *SubscriptionEvent
+	handler TaskHandler
+}
+
+// TaskRouter serves many task types, possibly on several topics, with a single pool of handlers. Every registered task
+// type gets its own task subscription, but concurrency and credits are shared by all of them: the credits are split
+// between all the partitions which are subscribed and their sum never exceeds the credits of the router.
+type TaskRouter struct {
+	client *Client
+	config *taskWorkerConfig
+
+	mu      sync.Mutex
+	routes  []*taskRoute
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewTaskRouter creates a task router. Options are those of the task worker, WithWorkerCredits sets the credits which
+// are shared by all the subscriptions of the router.
+func (c *Client) NewTaskRouter(opts ...TaskWorkerOption) (*TaskRouter, error) {
+	config, err := c.newTaskWorkerConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &TaskRouter{client: c, config: config}, nil
+}
+
+// Handle registers the handler for tasks of taskType on topic. Handlers can only be registered before Start.
+func (r *TaskRouter) Handle(topic, taskType string, handler TaskHandler) error {
+	if handler == nil {
+		return errNoTaskHandler
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.started {
+		return errRouterStarted
+	}
+	key := taskRouteKey{topic: topic, taskType: taskType}
+	for _, route := range r.routes {
+		if route.taskRouteKey == key {
+			return fmt.Errorf("handler for task type %s on topic %s already registered", taskType, topic)
+		}
+	}
+
+	r.routes = append(r.routes, &taskRoute{
+		taskRouteKey: key,
+		handler:      ChainTaskHandler(handler, r.config.middlewares...),
+	})
+	return nil
+}
+
+// HandleFunc registers the function as handler for tasks of taskType on topic.
+func (r *TaskRouter) HandleFunc(topic, taskType string, handler func(ctx context.Context, task *zbmsgpack.Task) (interface{}, error)) error {
+	return r.Handle(topic, taskType, TaskHandlerFunc(handler))
+}
+
+// Start opens the task subscriptions of all the registered handlers and starts handling tasks. When one of them can't
+// be opened, the ones opened before are closed again. The context only bounds opening of the subscriptions.
+func (r *TaskRouter) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.started {
+		return errRouterStarted
+	}
+	if len(r.routes) == 0 {
+		return errNoRoutes
+	}
+
+	credits, err := r.partitionCredits()
+	if err != nil {
+		return err
+	}
+
+	tasksCh := make(chan routedTask)
+	var forwarders sync.WaitGroup
+	for _, route := range r.routes {
+		eventsCh, subscription, err := r.client.taskConsumer(ctx, route.topic, r.config.lockOwner, route.taskType, r.config.lockDuration, credits)
+		if err != nil {
+			r.closeSubscriptions(context.Background())
+			go func() {
+				// Tasks which arrived in the meantime are dropped, their locks expire.
+				for range tasksCh {
+				}
+			}()
+			forwarders.Wait()
+			close(tasksCh)
+			return err
+		}
+		route.subscription = subscription
+
+		forwarders.Add(1)
+		go func(handler TaskHandler, eventsCh <-chan *SubscriptionEvent) {
+			defer forwarders.Done()
+			for event := range eventsCh {
+				tasksCh <- routedTask{event: event, handler: handler}
+			}
+		}(route.handler, eventsCh)
+	}
+
+	go func() {
+		forwarders.Wait()
+		close(tasksCh)
+	}()
+
+	handlerCtx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.started = true
+
+	for i := 0; i < r.config.concurrency; i++ {
+		r.wg.Add(1)
+		go r.work(handlerCtx, tasksCh)
+	}
+	return nil
+}
+
+// partitionCredits splits the credits of the router between all the partitions which are subscribed, every partition
+// gets at least one credit. The partitions share the credits of the router as budget, so a credit policy can only grow
+// a partition by the credits the others don't hold.
+func (r *TaskRouter) partitionCredits() (creditConfig, error) {
+	partitions := 0
+	for _, route := range r.routes {
+		addrs, err := r.client.topicPartitionsAddrs(route.topic)
+		if err != nil {
+			return creditConfig{}, err
+		}
+		partitions += len(*addrs)
+	}
+
+	credits := r.config.credits
+	if partitions > 0 {
+		credits /= int32(partitions)
+	}
+	if credits < 1 {
+		credits = 1
+	}
+	config := r.client.config.creditConfig(credits, r.config.creditPolicy)
+	config.budget = newCreditBudget(r.config.credits)
+	return config, nil
+}
+
+// Stop closes all the task subscriptions and waits until the tasks which are being handled are completed or failed.
+// When ctx is done before that, contexts of the handlers are cancelled and ctx.Err() is returned.
+func (r *TaskRouter) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.started {
+		return errRouterNotStarted
+	}
+
+	errs := r.closeSubscriptions(ctx)
+	r.started = false
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		r.cancel()
+	case <-ctx.Done():
+		r.cancel()
+		return ctx.Err()
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+func (r *TaskRouter) closeSubscriptions(ctx context.Context) []error {
+	var errs []error
+	for _, route := range r.routes {
+		if route.subscription == nil {
+			continue
+		}
+		errs = append(errs, r.client.closeTaskSubscription(ctx, route.subscription)...)
+		route.subscription = nil
+	}
+	return errs
+}
+
+func (r *TaskRouter) work(ctx context.Context, tasksCh <-chan routedTask) {
+	defer r.wg.Done()
+
+	for task := range tasksCh {
+		handleTask(ctx, r.client, r.config, task.handler, task.event)
+	}
+}
@@ -0,0 +1,97 @@
+package zbc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+)
+
+func TestCreditBudgetResize(t *testing.T) {
+	budget := newCreditBudget(10)
+	budget.take(4)
+	budget.take(4)
+
+	tests := []struct {
+		capacity, wanted, granted, held int32
+	}{
+		{4, 5, 5, 9},
+		{5, 8, 6, 10},
+		{4, 6, 4, 10},
+		{6, 2, 2, 6},
+		{4, 9, 8, 10},
+	}
+
+	for _, test := range tests {
+		if granted := budget.resize(test.capacity, test.wanted); granted != test.granted {
+			t.Fatalf("resize(%d, %d): expected %d, got %d", test.capacity, test.wanted, test.granted, granted)
+		}
+		if budget.held != test.held {
+			t.Fatalf("resize(%d, %d): expected %d held, got %d", test.capacity, test.wanted, test.held, budget.held)
+		}
+	}
+}
+
+func TestSharedBudgetLimitsAdaptivePartitions(t *testing.T) {
+	config := creditConfig{credits: 2, policy: &AdaptiveCreditPolicy{Min: 1, Max: 32, WaitFraction: 1}, budget: newCreditBudget(12)}
+
+	partitions := make([]*partitionCredits, 4)
+	for i := range partitions {
+		partitions[i] = newPartitionCredits(zbmsgpack.TaskSubscription{PartitionID: uint16(i), Credits: 2}, time.Minute, config)
+	}
+
+	// Tasks are taken quickly, so every partition tries to grow up to the maximum of the policy.
+	for i := 0; i < 20; i++ {
+		for _, pc := range partitions {
+			consumeAll(pc, 1, 0)
+		}
+	}
+
+	var total int32
+	for _, pc := range partitions {
+		state := pc.state()
+		if state.Capacity < 1 {
+			t.Fatalf("partition %d has no credits", state.PartitionID)
+		}
+		total += state.Capacity
+	}
+	if total != 12 {
+		t.Fatalf("expected partitions to hold the budget of 12 credits, got %d", total)
+	}
+}
+
+func TestTaskRouterHandle(t *testing.T) {
+	r := &TaskRouter{config: &taskWorkerConfig{}}
+	handler := TaskHandlerFunc(func(ctx context.Context, task *zbmsgpack.Task) (interface{}, error) {
+		return nil, nil
+	})
+
+	if err := r.Handle("default-topic", "foo", nil); err != errNoTaskHandler {
+		t.Fatalf("expected errNoTaskHandler, got %v", err)
+	}
+	if err := r.Handle("default-topic", "foo", handler); err != nil {
+		t.Fatalf("Handle failed: %s", err)
+	}
+	if err := r.Handle("default-topic", "foo", handler); err == nil {
+		t.Fatal("expected error for duplicate handler")
+	}
+	if err := r.Handle("other-topic", "foo", handler); err != nil {
+		t.Fatalf("Handle on other topic failed: %s", err)
+	}
+
+	r.started = true
+	if err := r.Handle("default-topic", "bar", handler); err != errRouterStarted {
+		t.Fatalf("expected errRouterStarted, got %v", err)
+	}
+}
+
+func TestTaskRouterStartWithoutRoutes(t *testing.T) {
+	r := &TaskRouter{config: &taskWorkerConfig{}}
+	if err := r.Start(context.Background()); err != errNoRoutes {
+		t.Fatalf("expected errNoRoutes, got %v", err)
+	}
+	if err := r.Stop(context.Background()); err != errRouterNotStarted {
+		t.Fatalf("expected errRouterNotStarted, got %v", err)
+	}
+}
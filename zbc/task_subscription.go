@@ -0,0 +1,201 @@
+package zbc
+
+import (
+	"context"
+	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+)
+
+// TaskSubscriptionReconnect describes a partition of a task subscription which is reopened because its socket was lost
+// or the leader of the partition moved.
+type TaskSubscriptionReconnect struct {
+	TaskType    string
+	PartitionID uint16
+
+	// PreviousSubscriberKey is the key of the subscription which was lost.
+	PreviousSubscriberKey uint64
+	// SubscriberKey is the key of the reopened subscription, it is zero if reopening failed.
+	SubscriberKey uint64
+	// Addr is the broker on which the subscription was reopened.
+	Addr string
+
+	// Err is set when reopening failed, it is tried again after a backoff.
+	Err error
+}
+
+// partitionSubscription hands the tasks of one partition of a task subscription to the consumer. It watches the socket
+// of the subscription and the cluster topology, and reopens the subscription on the leader of the partition when
+// either of them changes.
+type partitionSubscription struct {
+	rm    *requestManager
+	tsi   *zbmsgpack.TaskSubscriptionInfo
+	entry *taskSubscriptionEntry
+	index int
+
+	lockOwner    string
+	taskType     string
+	lockDuration time.Duration
+	credits      *partitionCredits
+	// partitions is the number of partitions of the subscription, which share the burst of the rate limit of taskType.
+	partitions int
+
+	sock     *socket
+	addr     string
+	eventsCh chan *SubscriptionEvent
+
+	// open and close send the subscription requests to the broker, see openPartition and closePartition.
+	open  func(ctx context.Context, partitionID uint16, credits int32) (*requestWrapper, *zbmsgpack.TaskSubscription, error)
+	close func(ctx context.Context, addr string, sub zbmsgpack.TaskSubscription)
+}
+
+func (ps *partitionSubscription) run(endSubscriptionCh chan<- *SubscriptionEvent) {
+	defer ps.entry.wg.Done()
+
+	doneCh := ps.entry.doneCh
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-doneCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		select {
+		case msg := <-ps.eventsCh:
+			if err := ps.rm.taskLimits.wait(ctx, ps.taskType); err != nil {
+				return
+			}
+			msg.setDeadline(ps.rm.config.lockSafetyMargin)
+			offered := time.Now()
+			select {
+			case endSubscriptionCh <- msg:
+				ps.rm.replenishCredits(ps.credits, msg, offered, ps.rm.taskLimits.partitionBurst(ps.taskType, ps.partitions))
+			case <-doneCh:
+				return
+			}
+
+		case <-ps.sock.closeCh:
+			ps.reopen()
+
+		case <-ps.rm.clusterChanged():
+			if ps.leaderMoved() {
+				ps.reopen()
+			}
+
+		case <-doneCh:
+			return
+		}
+	}
+}
+
+func (ps *partitionSubscription) leaderMoved() bool {
+	cluster := ps.rm.getCluster()
+	if cluster == nil {
+		return false
+	}
+	addr, ok := cluster.AddrByPartitionID[ps.credits.state().PartitionID]
+	return ok && addr != ps.addr
+}
+
+// reopen will open the subscription on the current leader of the partition, retrying with backoff until it succeeds or
+// the subscription is closed.
+func (ps *partitionSubscription) reopen() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-ps.entry.doneCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	previous := ps.credits.state()
+	ps.sock.removeTaskSubscription(previous.SubscriberKey)
+	if !ps.sock.isClosed() {
+		// Leader moved while the previous broker is still connected. It would keep pushing and locking tasks for the
+		// subscription which nobody takes, so it is closed there first. Failing to do so doesn't hold up reopening.
+		ps.close(ctx, ps.addr, ps.credits.taskSubscription())
+	}
+
+	b := &backoff{
+		Min:    ps.rm.config.backoffMin,
+		Max:    ps.rm.config.backoffMax,
+		Factor: 2,
+		Jitter: true,
+	}
+	for {
+		request, sub, err := ps.open(ctx, previous.PartitionID, previous.Capacity)
+		if ctx.Err() != nil || err == ErrClientClosed {
+			return
+		}
+
+		reconnect := TaskSubscriptionReconnect{
+			TaskType:              ps.taskType,
+			PartitionID:           previous.PartitionID,
+			PreviousSubscriberKey: previous.SubscriberKey,
+			Err:                   err,
+		}
+		if err == nil {
+			sub.PartitionID = previous.PartitionID
+			if !ps.resubscribed(request, *sub) {
+				return
+			}
+			reconnect.SubscriberKey = sub.SubscriberKey
+			reconnect.Addr = request.addr
+		}
+		ps.rm.config.reconnectHandler(reconnect)
+		if err == nil {
+			return
+		}
+
+		select {
+		case <-time.After(b.Duration()):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// resubscribed will switch the partition over to the reopened subscription. If the task subscription was closed in the
+// meantime, the reopened subscription is closed again and false is returned.
+func (ps *partitionSubscription) resubscribed(request *requestWrapper, sub zbmsgpack.TaskSubscription) bool {
+	if !ps.rm.replaceTaskSubscription(ps.tsi, ps.entry, ps.index, sub) {
+		ps.close(context.Background(), request.addr, sub)
+		return false
+	}
+
+	ps.credits.resubscribed(sub)
+	ps.sock = request.socket()
+	ps.addr = request.addr
+	ps.sock.addTaskSubscription(sub.SubscriberKey, ps.eventsCh)
+	return true
+}
+
+// openPartition will open the subscription on the leader of the partition.
+func (ps *partitionSubscription) openPartition(ctx context.Context, partitionID uint16, credits int32) (*requestWrapper, *zbmsgpack.TaskSubscription, error) {
+	message := ps.rm.openTaskSubscriptionRequest(partitionID, ps.lockOwner, ps.taskType, ps.lockDuration, credits)
+	request := newRequestWrapper(message)
+	resp, err := ps.rm.executeRequest(ctx, request)
+	if err != nil {
+		return request, nil, err
+	}
+
+	sub := ps.rm.unmarshalTaskSubscription(resp)
+	if sub == nil {
+		return request, nil, errResourceNotFound
+	}
+	return request, sub, nil
+}
+
+// closePartition will close the subscription on the broker at addr, which need not be the leader of the partition
+// anymore. It is best effort, the subscription is gone anyway once the broker drops the connection.
+func (ps *partitionSubscription) closePartition(ctx context.Context, addr string, sub zbmsgpack.TaskSubscription) {
+	request := newRequestWrapper(ps.rm.closeTaskSubscriptionRequest(&sub))
+	request.addr = addr
+	ps.rm.executeRequest(ctx, request)
+}
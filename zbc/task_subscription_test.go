@@ -0,0 +1,320 @@
+package zbc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+	"github.com/zeebe-io/zbc-go/zbc/zbsbe"
+)
+
+var errTestBrokerDown = errors.New("broker down")
+
+func newTestSocket() *socket {
+	client, _ := net.Pipe()
+	closeCh := make(chan struct{})
+	return &socket{
+		dispatcher: newDispatcher(closeCh, new(uint64)),
+		connection: client,
+		stream:     make([]byte, 0),
+		chunkSize:  SocketChunkSize,
+		closeCh:    closeCh,
+	}
+}
+
+// partitionSubscriptionTest runs a partition subscription of subscriber 10 on partition 1 of broker-1. Requests to the
+// broker are recorded in calls, opening returns the results in opens one by one and then keeps returning the last one.
+type partitionSubscriptionTest struct {
+	ps         *partitionSubscription
+	tasksCh    chan *SubscriptionEvent
+	reconnects chan TaskSubscriptionReconnect
+	calls      chan string
+
+	opens []openResult
+}
+
+type openResult struct {
+	sock *socket
+	addr string
+	key  uint64
+	err  error
+}
+
+func newPartitionSubscriptionTest(opens ...openResult) *partitionSubscriptionTest {
+	pt := &partitionSubscriptionTest{
+		tasksCh:    make(chan *SubscriptionEvent),
+		reconnects: make(chan TaskSubscriptionReconnect, 10),
+		calls:      make(chan string, 10),
+		opens:      opens,
+	}
+
+	config := newClientConfig()
+	config.backoffMin = 20 * time.Millisecond
+	config.backoffMax = 40 * time.Millisecond
+	config.reconnectHandler = func(reconnect TaskSubscriptionReconnect) {
+		pt.reconnects <- reconnect
+	}
+
+	// Transport is closed, so nothing reaches a broker unless the test stubs it.
+	closed := make(chan struct{})
+	close(closed)
+	rm := &requestManager{
+		requestFactory:     newRequestFactory(),
+		responseHandler:    newResponseHandler(),
+		topologyManager:    &topologyManager{transportManager: &transportManager{config: config, closeCh: closed}, clusterChangedCh: make(chan struct{})},
+		taskSubscriptions:  make(map[*zbmsgpack.TaskSubscriptionInfo]*taskSubscriptionEntry),
+		topicSubscriptions: make(map[*zbmsgpack.TopicSubscriptionInfo]chan struct{}),
+		taskLimits:         newRateLimits(nil),
+		commandLimits:      newRateLimits(nil),
+	}
+
+	tsi := zbmsgpack.NewTaskSubscriptionInfo()
+	entry := rm.addTaskSubscriptionInfo(tsi)
+	sub := zbmsgpack.TaskSubscription{PartitionID: 1, SubscriberKey: 10, Credits: 4}
+	pc := newPartitionCredits(sub, time.Minute, creditConfig{credits: 4, policy: FixedCreditPolicy{}})
+
+	pt.ps = &partitionSubscription{
+		rm:           rm,
+		tsi:          tsi,
+		entry:        entry,
+		index:        rm.addTaskSubscriptionPartition(tsi, entry, sub, pc),
+		lockOwner:    "owner",
+		taskType:     "foo",
+		lockDuration: time.Minute,
+		credits:      pc,
+		sock:         newTestSocket(),
+		addr:         "broker-1",
+		eventsCh:     make(chan *SubscriptionEvent, 4),
+	}
+	pt.ps.open = pt.open
+	pt.ps.close = pt.close
+	pt.ps.sock.addTaskSubscription(sub.SubscriberKey, pt.ps.eventsCh)
+	return pt
+}
+
+func (pt *partitionSubscriptionTest) open(ctx context.Context, partitionID uint16, credits int32) (*requestWrapper, *zbmsgpack.TaskSubscription, error) {
+	pt.calls <- fmt.Sprintf("open %d with %d credits", partitionID, credits)
+
+	result := pt.opens[0]
+	if len(pt.opens) > 1 {
+		pt.opens = pt.opens[1:]
+	}
+	if result.err != nil {
+		return nil, nil, result.err
+	}
+
+	request := newRequestWrapper(nil)
+	request.setSocket(result.sock)
+	request.addr = result.addr
+	return request, &zbmsgpack.TaskSubscription{SubscriberKey: result.key, Credits: credits}, nil
+}
+
+func (pt *partitionSubscriptionTest) close(ctx context.Context, addr string, sub zbmsgpack.TaskSubscription) {
+	pt.calls <- fmt.Sprintf("close %d on %s", sub.SubscriberKey, addr)
+}
+
+func (pt *partitionSubscriptionTest) run() chan struct{} {
+	done := make(chan struct{})
+	pt.ps.entry.wg.Add(1)
+	go func() {
+		pt.ps.run(pt.tasksCh)
+		close(done)
+	}()
+	return done
+}
+
+func (pt *partitionSubscriptionTest) reconnect(t *testing.T) TaskSubscriptionReconnect {
+	select {
+	case reconnect := <-pt.reconnects:
+		return reconnect
+	case <-time.After(5 * time.Second):
+		t.Fatal("partition wasn't reopened")
+		return TaskSubscriptionReconnect{}
+	}
+}
+
+func (pt *partitionSubscriptionTest) expectCalls(t *testing.T, expected ...string) {
+	for _, call := range expected {
+		select {
+		case got := <-pt.calls:
+			if got != call {
+				t.Fatalf("expected %s, got %s", call, got)
+			}
+		default:
+			t.Fatalf("expected %s, got nothing", call)
+		}
+	}
+	select {
+	case got := <-pt.calls:
+		t.Fatalf("unexpected %s", got)
+	default:
+	}
+}
+
+func (pt *partitionSubscriptionTest) stop(t *testing.T, done chan struct{}) {
+	pt.ps.rm.removeTaskSubscriptionInfo(pt.ps.tsi)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("partition subscription didn't stop")
+	}
+}
+
+func (pt *partitionSubscriptionTest) subscription() zbmsgpack.TaskSubscription {
+	pt.ps.rm.subscriptionsMu.Lock()
+	defer pt.ps.rm.subscriptionsMu.Unlock()
+	return pt.ps.tsi.Subs[pt.ps.index]
+}
+
+func expectReconnect(t *testing.T, reconnect TaskSubscriptionReconnect, key uint64, addr string) {
+	expected := TaskSubscriptionReconnect{TaskType: "foo", PartitionID: 1, PreviousSubscriberKey: 10, SubscriberKey: key, Addr: addr}
+	if reconnect != expected {
+		t.Fatalf("expected reconnect %+v, got %+v", expected, reconnect)
+	}
+}
+
+func TestPartitionSubscriptionReopensOnSocketLoss(t *testing.T) {
+	sock := newTestSocket()
+	pt := newPartitionSubscriptionTest(openResult{sock: sock, addr: "broker-2", key: 20})
+	previous := pt.ps.sock
+	done := pt.run()
+
+	previous.teardown()
+	expectReconnect(t, pt.reconnect(t), 20, "broker-2")
+
+	// Connection to the previous broker is gone together with the subscription, nothing is closed there.
+	pt.expectCalls(t, "open 1 with 4 credits")
+	if sub := pt.subscription(); sub.SubscriberKey != 20 || sub.PartitionID != 1 {
+		t.Fatalf("unexpected subscription %+v", sub)
+	}
+	if state := pt.ps.credits.state(); state.SubscriberKey != 20 || state.Remaining != 4 {
+		t.Fatalf("unexpected credit state %+v", state)
+	}
+	if previous.subscriptions.getTaskChannel(10) != nil {
+		t.Fatal("previous socket still routes the previous subscriber")
+	}
+
+	// Tasks of the reopened subscription reach the consumer.
+	go sock.dispatchTaskEvent(20, &zbsbe.SubscribedEvent{PartitionId: 1, SubscriberKey: 20}, NewTask("foo", "owner"))
+	select {
+	case event := <-pt.tasksCh:
+		if event.Event.SubscriberKey != 20 {
+			t.Fatalf("unexpected event %+v", event.Event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("task of the reopened subscription wasn't delivered")
+	}
+
+	pt.stop(t, done)
+}
+
+func TestPartitionSubscriptionReopensOnLeaderMove(t *testing.T) {
+	pt := newPartitionSubscriptionTest(openResult{sock: newTestSocket(), addr: "broker-2", key: 20})
+	previous := pt.ps.sock
+	done := pt.run()
+
+	// Topology updates which keep the leader don't reopen the subscription.
+	pt.ps.rm.setCluster(&zbmsgpack.ClusterTopology{AddrByPartitionID: map[uint16]string{1: "broker-1"}})
+	select {
+	case reconnect := <-pt.reconnects:
+		t.Fatalf("unexpected reconnect %+v", reconnect)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Topology is refreshed periodically, an update the subscription misses while it is busy is seen with the next one.
+	var reconnect TaskSubscriptionReconnect
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(5 * time.Second)
+	for reopened := false; !reopened; {
+		pt.ps.rm.setCluster(&zbmsgpack.ClusterTopology{AddrByPartitionID: map[uint16]string{1: "broker-2"}})
+		select {
+		case reconnect = <-pt.reconnects:
+			reopened = true
+		case <-ticker.C:
+		case <-timeout:
+			t.Fatal("partition wasn't reopened")
+		}
+	}
+	expectReconnect(t, reconnect, 20, "broker-2")
+
+	// Previous broker is still connected, so the subscription is closed there before it is reopened.
+	pt.expectCalls(t, "close 10 on broker-1", "open 1 with 4 credits")
+	if previous.isClosed() {
+		t.Fatal("socket of the previous broker was closed")
+	}
+	if previous.subscriptions.getTaskChannel(10) != nil {
+		t.Fatal("previous socket still routes the previous subscriber")
+	}
+	if sub := pt.subscription(); sub.SubscriberKey != 20 {
+		t.Fatalf("unexpected subscription %+v", sub)
+	}
+
+	pt.stop(t, done)
+}
+
+func TestPartitionSubscriptionReopenBackoff(t *testing.T) {
+	pt := newPartitionSubscriptionTest(
+		openResult{err: errTestBrokerDown},
+		openResult{err: errTestBrokerDown},
+		openResult{sock: newTestSocket(), addr: "broker-2", key: 20},
+	)
+	done := pt.run()
+
+	started := time.Now()
+	pt.ps.sock.teardown()
+
+	// Every failed attempt is reported, and retried after the backoff.
+	for i := 0; i < 2; i++ {
+		reconnect := pt.reconnect(t)
+		if reconnect.Err != errTestBrokerDown || reconnect.SubscriberKey != 0 || len(reconnect.Addr) != 0 {
+			t.Fatalf("attempt %d: unexpected reconnect %+v", i, reconnect)
+		}
+	}
+	expectReconnect(t, pt.reconnect(t), 20, "broker-2")
+	if elapsed := time.Since(started); elapsed < 40*time.Millisecond {
+		t.Fatalf("expected at least two backoffs of 20ms, reopened after %s", elapsed)
+	}
+	pt.expectCalls(t, "open 1 with 4 credits", "open 1 with 4 credits", "open 1 with 4 credits")
+
+	pt.stop(t, done)
+}
+
+func TestPartitionSubscriptionClosedWhileReopening(t *testing.T) {
+	pt := newPartitionSubscriptionTest(openResult{err: errTestBrokerDown})
+	done := pt.run()
+
+	pt.ps.sock.teardown()
+	if reconnect := pt.reconnect(t); reconnect.Err != errTestBrokerDown {
+		t.Fatalf("unexpected reconnect %+v", reconnect)
+	}
+
+	// Closing the subscription stops the retries.
+	pt.stop(t, done)
+	for len(pt.calls) > 0 {
+		<-pt.calls
+	}
+	time.Sleep(100 * time.Millisecond)
+	pt.expectCalls(t)
+}
+
+func TestPartitionSubscriptionResubscribedAfterClose(t *testing.T) {
+	pt := newPartitionSubscriptionTest()
+	pt.ps.rm.removeTaskSubscriptionInfo(pt.ps.tsi)
+
+	// Subscription was closed while it was reopened, so the reopened one is closed on its broker again.
+	request := newRequestWrapper(nil)
+	request.setSocket(newTestSocket())
+	request.addr = "broker-2"
+	if pt.ps.resubscribed(request, zbmsgpack.TaskSubscription{PartitionID: 1, SubscriberKey: 20}) {
+		t.Fatal("closed subscription was resubscribed")
+	}
+	pt.expectCalls(t, "close 20 on broker-2")
+	if sub := pt.subscription(); sub.SubscriberKey != 10 {
+		t.Fatalf("unexpected subscription %+v", sub)
+	}
+}
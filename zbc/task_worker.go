@@ -0,0 +1,278 @@
+package zbc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+)
+
+var (
+	errWorkerStarted            = errors.New("task worker already started")
+	errWorkerNotStarted         = errors.New("task worker not started")
+	errNoTaskHandler            = errors.New("task handler is required")
+	errInvalidWorkerConcurrency = errors.New("task worker concurrency must be positive")
+	errNoWorkerLockOwner        = errors.New("task worker lock owner is required")
+)
+
+// TaskHandler processes tasks received by a TaskWorker. The returned payload, if not nil, replaces the payload of the
+// task when it is completed. Returning an error fails the task, its message is stored in TaskErrorMessageHeader.
+type TaskHandler interface {
+	Handle(ctx context.Context, task *zbmsgpack.Task) (interface{}, error)
+}
+
+// TaskHandlerFunc is an adapter to use an ordinary function as TaskHandler.
+type TaskHandlerFunc func(ctx context.Context, task *zbmsgpack.Task) (interface{}, error)
+
+// Handle calls f(ctx, task).
+func (f TaskHandlerFunc) Handle(ctx context.Context, task *zbmsgpack.Task) (interface{}, error) {
+	return f(ctx, task)
+}
+
+type taskWorkerConfig struct {
+	concurrency  int
+	lockOwner    string
+	lockDuration time.Duration
+	credits      int32
+	creditPolicy CreditPolicy
+	middlewares  []TaskMiddleware
+	errorHandler func(task *zbmsgpack.Task, err error)
+}
+
+func (cfg *taskWorkerConfig) validate() error {
+	if cfg.concurrency <= 0 {
+		return errInvalidWorkerConcurrency
+	}
+	if len(cfg.lockOwner) == 0 {
+		return errNoWorkerLockOwner
+	}
+	if cfg.lockDuration < time.Millisecond {
+		return errInvalidLockDuration
+	}
+	if cfg.credits <= 0 {
+		return errInvalidTaskCredits
+	}
+	return nil
+}
+
+func (c *Client) newTaskWorkerConfig(opts []TaskWorkerOption) (*taskWorkerConfig, error) {
+	config := &taskWorkerConfig{
+		concurrency:  TaskWorkerConcurrency,
+		lockOwner:    TaskWorkerLockOwner,
+		lockDuration: c.config.lockDuration,
+		credits:      c.config.taskCredits,
+		errorHandler: func(*zbmsgpack.Task, error) {},
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// TaskWorkerOption is used to configure the task worker on construction.
+type TaskWorkerOption func(*taskWorkerConfig)
+
+// WithWorkerConcurrency sets how many tasks are handled at once.
+func WithWorkerConcurrency(concurrency int) TaskWorkerOption {
+	return func(cfg *taskWorkerConfig) {
+		cfg.concurrency = concurrency
+	}
+}
+
+// WithWorkerLockOwner sets the lock owner of the task subscription.
+func WithWorkerLockOwner(lockOwner string) TaskWorkerOption {
+	return func(cfg *taskWorkerConfig) {
+		cfg.lockOwner = lockOwner
+	}
+}
+
+// WithWorkerLockDuration sets for how long tasks are locked to the worker.
+func WithWorkerLockDuration(duration time.Duration) TaskWorkerOption {
+	return func(cfg *taskWorkerConfig) {
+		cfg.lockDuration = duration
+	}
+}
+
+// WithWorkerCredits sets the number of credits with which every partition of the task subscription is opened.
+func WithWorkerCredits(credits int32) TaskWorkerOption {
+	return func(cfg *taskWorkerConfig) {
+		cfg.credits = credits
+	}
+}
+
+// WithWorkerCreditPolicy sets the credit policy of the task subscription, overriding the one of the client.
+func WithWorkerCreditPolicy(policy CreditPolicy) TaskWorkerOption {
+	return func(cfg *taskWorkerConfig) {
+		cfg.creditPolicy = policy
+	}
+}
+
+// WithWorkerMiddleware adds middlewares around the handler of the worker, see ChainTaskHandler. Use
+// RecoveryMiddleware to fail tasks whose handler panics instead of crashing the process.
+func WithWorkerMiddleware(middlewares ...TaskMiddleware) TaskWorkerOption {
+	return func(cfg *taskWorkerConfig) {
+		cfg.middlewares = append(cfg.middlewares, middlewares...)
+	}
+}
+
+// WithWorkerErrorHandler sets the function which is called when completing or failing a task doesn't succeed.
+func WithWorkerErrorHandler(handler func(task *zbmsgpack.Task, err error)) TaskWorkerOption {
+	return func(cfg *taskWorkerConfig) {
+		cfg.errorHandler = handler
+	}
+}
+
+// TaskWorker opens a task subscription and hands every task to its TaskHandler. Tasks are completed when the handler
+// succeeds and failed when it returns an error. Credits are given back to the broker as tasks are taken by the handlers.
+// The context passed to the handler is cancelled when the deadline of the task passes.
+type TaskWorker struct {
+	client   *Client
+	topic    string
+	taskType string
+	handler  TaskHandler
+	config   *taskWorkerConfig
+
+	mu           sync.Mutex
+	subscription *zbmsgpack.TaskSubscriptionInfo
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// NewTaskWorker creates a task worker for tasks of taskType on topic. The worker doesn't subscribe before Start is called.
+func (c *Client) NewTaskWorker(topic, taskType string, handler TaskHandler, opts ...TaskWorkerOption) (*TaskWorker, error) {
+	if handler == nil {
+		return nil, errNoTaskHandler
+	}
+
+	config, err := c.newTaskWorkerConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TaskWorker{
+		client:   c,
+		topic:    topic,
+		taskType: taskType,
+		handler:  ChainTaskHandler(handler, config.middlewares...),
+		config:   config,
+	}, nil
+}
+
+// Start opens the task subscription and starts handling tasks. The context only bounds opening of the subscription.
+func (w *TaskWorker) Start(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.subscription != nil {
+		return errWorkerStarted
+	}
+
+	credits := w.client.config.creditConfig(w.config.credits, w.config.creditPolicy)
+	tasksCh, subscription, err := w.client.taskConsumer(ctx, w.topic, w.config.lockOwner, w.taskType, w.config.lockDuration, credits)
+	if err != nil {
+		return err
+	}
+
+	handlerCtx, cancel := context.WithCancel(context.Background())
+	w.subscription = subscription
+	w.cancel = cancel
+
+	for i := 0; i < w.config.concurrency; i++ {
+		w.wg.Add(1)
+		go w.work(handlerCtx, tasksCh)
+	}
+	return nil
+}
+
+// Stop closes the task subscription and waits until the tasks which are being handled are completed or failed. When
+// ctx is done before that, contexts of the handlers are cancelled and ctx.Err() is returned.
+func (w *TaskWorker) Stop(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.subscription == nil {
+		return errWorkerNotStarted
+	}
+
+	errs := w.client.closeTaskSubscription(ctx, w.subscription)
+	w.subscription = nil
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		w.cancel()
+	case <-ctx.Done():
+		w.cancel()
+		return ctx.Err()
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// CreditState returns the credit state of every partition of the task subscription, or nil if the worker isn't started.
+func (w *TaskWorker) CreditState() []PartitionCredits {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.subscription == nil {
+		return nil
+	}
+	return w.client.creditState(w.subscription)
+}
+
+func (w *TaskWorker) work(ctx context.Context, tasksCh <-chan *SubscriptionEvent) {
+	defer w.wg.Done()
+
+	for event := range tasksCh {
+		handleTask(ctx, w.client, w.config, w.handler, event)
+	}
+}
+
+// handleTask hands the task to handler and completes or fails it depending on the result.
+func handleTask(ctx context.Context, c *Client, config *taskWorkerConfig, handler TaskHandler, event *SubscriptionEvent) {
+	if event.Task == nil {
+		return
+	}
+
+	if deadline, ok := event.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	payload, err := handler.Handle(ctx, event.Task)
+	if event.LockExpired() {
+		// Lock expired while the task was handled, the broker hands it out again.
+		config.errorHandler(event.Task, ErrTaskLockExpired)
+		return
+	}
+	if ctx.Err() != nil {
+		// Worker was stopped forcefully, the lock will expire and the task is handed out again.
+		return
+	}
+
+	if err != nil {
+		_, err = c.failTaskWithError(context.Background(), event, err)
+	} else if payload != nil {
+		_, err = c.completeTaskWithPayload(context.Background(), event, payload, false)
+	} else {
+		_, err = c.completeTask(context.Background(), event)
+	}
+
+	if err != nil {
+		config.errorHandler(event.Task, err)
+	}
+}
@@ -0,0 +1,70 @@
+package zbc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+)
+
+// newTestTaskWorker returns a worker without client, so it must not send any command for the tasks it handles.
+func newTestTaskWorker(handler TaskHandlerFunc, errs chan<- error) *TaskWorker {
+	return &TaskWorker{
+		handler: handler,
+		config: &taskWorkerConfig{
+			errorHandler: func(task *zbmsgpack.Task, err error) {
+				errs <- err
+			},
+		},
+	}
+}
+
+func TestTaskWorkerHandlerDeadline(t *testing.T) {
+	handlerErr := make(chan error, 1)
+	errs := make(chan error, 1)
+	worker := newTestTaskWorker(func(ctx context.Context, task *zbmsgpack.Task) (interface{}, error) {
+		<-ctx.Done()
+		handlerErr <- ctx.Err()
+		return nil, ctx.Err()
+	}, errs)
+
+	// Deadline passes while the handler is running, so its context is cancelled and the task isn't completed.
+	event := lockedTaskEvent(time.Now().Add(1050*time.Millisecond), time.Second)
+	done := make(chan struct{})
+	go func() {
+		handleTask(context.Background(), worker.client, worker.config, worker.handler, event)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler wasn't cancelled at the deadline")
+	}
+	if err := <-handlerErr; err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded in the handler, got %v", err)
+	}
+	if err := <-errs; err != ErrTaskLockExpired {
+		t.Fatalf("expected ErrTaskLockExpired, got %v", err)
+	}
+}
+
+func TestTaskWorkerHandlerStopped(t *testing.T) {
+	errs := make(chan error, 1)
+	worker := newTestTaskWorker(func(ctx context.Context, task *zbmsgpack.Task) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, errs)
+
+	// Worker is stopped forcefully, the task is left to the lock expiry without reporting an error.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	handleTask(ctx, worker.client, worker.config, worker.handler, lockedTaskEvent(time.Now().Add(time.Minute), time.Second))
+
+	select {
+	case err := <-errs:
+		t.Fatalf("unexpected error %v", err)
+	default:
+	}
+}
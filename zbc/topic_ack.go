@@ -0,0 +1,182 @@
+package zbc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
+)
+
+var errInvalidAckBatch = errors.New("ack batch size must be positive and interval must not be negative")
+
+type topicConsumerConfig struct {
+	manualAck     bool
+	batchSize     int
+	batchInterval time.Duration
+	errorHandler  func(event *SubscriptionEvent, err error)
+}
+
+func (cfg *topicConsumerConfig) validate() error {
+	if cfg.batchSize < 1 || cfg.batchInterval < 0 {
+		return errInvalidAckBatch
+	}
+	return nil
+}
+
+func newTopicConsumerConfig(opts []TopicConsumerOption) (*topicConsumerConfig, error) {
+	config := &topicConsumerConfig{
+		batchSize:    1,
+		errorHandler: func(*SubscriptionEvent, error) {},
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// TopicConsumerOption is used to configure how events of a topic subscription are acknowledged. By default an event
+// is acknowledged as soon as it is handed to the consumer.
+type TopicConsumerOption func(*topicConsumerConfig)
+
+// WithManualAck leaves acknowledging to the consumer, which calls Ack on the events it processed.
+func WithManualAck() TopicConsumerOption {
+	return func(cfg *topicConsumerConfig) {
+		cfg.manualAck = true
+	}
+}
+
+// WithAckBatch acknowledges the events of a partition once size of them are processed or interval passed, whichever
+// comes first. Only the highest position is acknowledged, since that covers all the events before it. An interval of
+// zero only acknowledges full batches.
+func WithAckBatch(size int, interval time.Duration) TopicConsumerOption {
+	return func(cfg *topicConsumerConfig) {
+		cfg.batchSize = size
+		cfg.batchInterval = interval
+	}
+}
+
+// WithAckErrorHandler sets the function which is called when acknowledging events fails. The event is the one with
+// the highest position of the failed ack, the next ack of the partition covers it again.
+func WithAckErrorHandler(handler func(event *SubscriptionEvent, err error)) TopicConsumerOption {
+	return func(cfg *topicConsumerConfig) {
+		cfg.errorHandler = handler
+	}
+}
+
+// TopicEventHandler processes the events of a topic subscription, see Client.HandleTopic.
+type TopicEventHandler func(event *SubscriptionEvent)
+
+// topicAcker acknowledges the processed events of one partition of a topic subscription. Acks are sent from its own
+// goroutine, so marking an event as processed never blocks.
+type topicAcker struct {
+	send   func(ctx context.Context, event *SubscriptionEvent) error
+	config *topicConsumerConfig
+
+	mu      sync.Mutex
+	pending *SubscriptionEvent
+	count   int
+
+	flushCh chan struct{}
+}
+
+// ack will mark the event as processed.
+func (a *topicAcker) ack(event *SubscriptionEvent) {
+	a.mu.Lock()
+	if a.pending == nil || event.Event.Position > a.pending.Event.Position {
+		a.pending = event
+	}
+	a.count++
+	full := a.count >= a.config.batchSize
+	a.mu.Unlock()
+
+	if full {
+		select {
+		case a.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (a *topicAcker) flush() {
+	a.mu.Lock()
+	event := a.pending
+	a.pending = nil
+	a.count = 0
+	a.mu.Unlock()
+
+	if event == nil {
+		return
+	}
+
+	if err := a.send(context.Background(), event); err != nil {
+		a.config.errorHandler(event, err)
+	}
+}
+
+// run will send the acks until doneCh is closed, the events processed by then are acknowledged before it returns.
+func (a *topicAcker) run(doneCh <-chan struct{}) {
+	var tickCh <-chan time.Time
+	if a.config.batchInterval > 0 {
+		ticker := time.NewTicker(a.config.batchInterval)
+		defer ticker.Stop()
+		tickCh = ticker.C
+	}
+
+	for {
+		select {
+		case <-a.flushCh:
+			a.flush()
+		case <-tickCh:
+			a.flush()
+		case <-doneCh:
+			a.flush()
+			return
+		}
+	}
+}
+
+// newTopicAcker creates the acker of the partition of the topic subscription.
+func (rm *requestManager) newTopicAcker(subscription *zbmsgpack.TopicSubscription, config *topicConsumerConfig) *topicAcker {
+	send := func(ctx context.Context, event *SubscriptionEvent) error {
+		ctx, cancel := context.WithTimeout(ctx, rm.config.requestTimeout)
+		defer cancel()
+		_, err := rm.topicSubscriptionAck(ctx, subscription, event)
+		return err
+	}
+	return newTopicAcker(send, config)
+}
+
+func newTopicAcker(send func(ctx context.Context, event *SubscriptionEvent) error, config *topicConsumerConfig) *topicAcker {
+	return &topicAcker{
+		send:    send,
+		config:  config,
+		flushCh: make(chan struct{}, 1),
+	}
+}
+
+// handleTopic hands every event of the subscription to handler and acknowledges it after the handler returned.
+func (rm *requestManager) handleTopic(ctx context.Context, topic, subName string, startPosition int64, handler TopicEventHandler, opts []TopicConsumerOption) (*zbmsgpack.TopicSubscriptionInfo, error) {
+	config, err := newTopicConsumerConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	config.manualAck = true
+
+	subscriptionCh, tsi, err := rm.topicConsumer(ctx, topic, subName, startPosition, config)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for event := range subscriptionCh {
+			handler(event)
+			event.Ack()
+		}
+	}()
+	return tsi, nil
+}
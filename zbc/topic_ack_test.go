@@ -0,0 +1,183 @@
+package zbc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zeebe-io/zbc-go/zbc/zbsbe"
+)
+
+// recordingSender records the positions of the acks sent by an acker.
+type recordingSender struct {
+	mu        sync.Mutex
+	positions []uint64
+	err       error
+	sentCh    chan struct{}
+}
+
+func (s *recordingSender) send(ctx context.Context, event *SubscriptionEvent) error {
+	s.mu.Lock()
+	s.positions = append(s.positions, event.Event.Position)
+	s.mu.Unlock()
+	s.sentCh <- struct{}{}
+	return s.err
+}
+
+func (s *recordingSender) waitFor(t *testing.T, n int) []uint64 {
+	for i := 0; i < n; i++ {
+		select {
+		case <-s.sentCh:
+		case <-time.After(time.Second):
+			t.Fatalf("expected %d acks, got %d", n, i)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]uint64(nil), s.positions...)
+}
+
+func newTestAcker(t *testing.T, err error, opts ...TopicConsumerOption) (*topicAcker, *recordingSender) {
+	config, configErr := newTopicConsumerConfig(opts)
+	if configErr != nil {
+		t.Fatalf("invalid config: %s", configErr)
+	}
+	sender := &recordingSender{err: err, sentCh: make(chan struct{}, 100)}
+	return newTopicAcker(sender.send, config), sender
+}
+
+func topicEvent(acker *topicAcker, position uint64) *SubscriptionEvent {
+	return &SubscriptionEvent{Event: &zbsbe.SubscribedEvent{Position: position}, acker: acker}
+}
+
+// flushRequested reports whether the acker asked for a flush.
+func flushRequested(acker *topicAcker) bool {
+	select {
+	case <-acker.flushCh:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestTopicAckerBatch(t *testing.T) {
+	acker, sender := newTestAcker(t, nil, WithAckBatch(3, 0))
+
+	topicEvent(acker, 1).Ack()
+	topicEvent(acker, 2).Ack()
+	if flushRequested(acker) {
+		t.Fatal("flush requested before the batch is full")
+	}
+	topicEvent(acker, 3).Ack()
+	if !flushRequested(acker) {
+		t.Fatal("no flush requested for full batch")
+	}
+	acker.flush()
+
+	// Out of order acks are acknowledged at the highest position.
+	for _, position := range []uint64{6, 4, 5} {
+		topicEvent(acker, position).Ack()
+	}
+	if !flushRequested(acker) {
+		t.Fatal("no flush requested for full batch")
+	}
+	acker.flush()
+	acker.flush()
+
+	if positions := sender.waitFor(t, 2); len(positions) != 2 || positions[0] != 3 || positions[1] != 6 {
+		t.Fatalf("expected acks at 3 and 6, got %v", positions)
+	}
+}
+
+func TestTopicAckerFlushesOnStop(t *testing.T) {
+	acker, sender := newTestAcker(t, nil, WithAckBatch(3, 0))
+	doneCh := make(chan struct{})
+	stoppedCh := make(chan struct{})
+	go func() {
+		acker.run(doneCh)
+		close(stoppedCh)
+	}()
+
+	topicEvent(acker, 7).Ack()
+	close(doneCh)
+	<-stoppedCh
+	if positions := sender.waitFor(t, 1); len(positions) != 1 || positions[0] != 7 {
+		t.Fatalf("expected incomplete batch to be acknowledged on stop, got %v", positions)
+	}
+}
+
+func TestTopicAckerInterval(t *testing.T) {
+	acker, sender := newTestAcker(t, nil, WithAckBatch(100, 10*time.Millisecond))
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	go acker.run(doneCh)
+
+	topicEvent(acker, 1).Ack()
+	topicEvent(acker, 2).Ack()
+	if positions := sender.waitFor(t, 1); positions[0] != 2 {
+		t.Fatalf("expected ack at 2 after the interval, got %v", positions)
+	}
+}
+
+func TestTopicAckerDoesntBlock(t *testing.T) {
+	acker, _ := newTestAcker(t, nil)
+
+	// Nobody sends the acks, marking events as processed must not block nevertheless.
+	done := make(chan struct{})
+	go func() {
+		for position := uint64(1); position <= 1000; position++ {
+			acker.ack(topicEvent(acker, position))
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ack blocked")
+	}
+}
+
+func TestTopicAckerReportsErrors(t *testing.T) {
+	errAck := errors.New("ack rejected")
+	reported := make(chan uint64, 1)
+	acker, sender := newTestAcker(t, errAck, WithAckErrorHandler(func(event *SubscriptionEvent, err error) {
+		if err == errAck {
+			reported <- event.Event.Position
+		}
+	}))
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	go acker.run(doneCh)
+
+	topicEvent(acker, 4).Ack()
+	sender.waitFor(t, 1)
+	select {
+	case position := <-reported:
+		if position != 4 {
+			t.Fatalf("expected failed ack at 4, got %d", position)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("failed ack wasn't reported")
+	}
+}
+
+func TestTopicConsumerConfig(t *testing.T) {
+	if _, err := newTopicConsumerConfig([]TopicConsumerOption{WithAckBatch(0, 0)}); err != errInvalidAckBatch {
+		t.Fatalf("expected errInvalidAckBatch, got %v", err)
+	}
+	if _, err := newTopicConsumerConfig([]TopicConsumerOption{WithAckBatch(1, -time.Second)}); err != errInvalidAckBatch {
+		t.Fatalf("expected errInvalidAckBatch, got %v", err)
+	}
+
+	config, err := newTopicConsumerConfig([]TopicConsumerOption{WithManualAck()})
+	if err != nil || !config.manualAck || config.batchSize != 1 {
+		t.Fatalf("unexpected config %+v, %v", config, err)
+	}
+
+	// Events which weren't delivered by a topic subscription can be acknowledged as well.
+	(&SubscriptionEvent{}).Ack()
+	(&IncidentEvent{}).Ack()
+}
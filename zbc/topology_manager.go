@@ -1,8 +1,10 @@
 package zbc
 
 import (
+	"context"
 	"errors"
 	"math/rand"
+	"sync"
 
 	"github.com/zeebe-io/zbc-go/zbc/zbmsgpack"
 
@@ -18,18 +20,48 @@ type topologyManager struct {
 	*transportManager
 
 	topologyWorkload chan *requestWrapper
+	inFlight         chan struct{}
 
 	lastIndexes map[string]uint16
 
-	bootstrapAddr string
-	cluster       *zbmsgpack.ClusterTopology
+	bootstrapAddrs []string
+
+	clusterMu        sync.RWMutex
+	cluster          *zbmsgpack.ClusterTopology
+	clusterChangedCh chan struct{}
+}
+
+func (tm *topologyManager) getCluster() *zbmsgpack.ClusterTopology {
+	tm.clusterMu.RLock()
+	defer tm.clusterMu.RUnlock()
+	return tm.cluster
+}
+
+func (tm *topologyManager) setCluster(cluster *zbmsgpack.ClusterTopology) {
+	tm.clusterMu.Lock()
+	tm.cluster = cluster
+	close(tm.clusterChangedCh)
+	tm.clusterChangedCh = make(chan struct{})
+	tm.clusterMu.Unlock()
+}
+
+// clusterChanged returns the channel which is closed when the cluster topology is updated.
+func (tm *topologyManager) clusterChanged() <-chan struct{} {
+	tm.clusterMu.RLock()
+	defer tm.clusterMu.RUnlock()
+	return tm.clusterChangedCh
 }
 
 func (tm *topologyManager) topicPartitionsAddrs(topic string) (*map[uint16]string, error) {
+	cluster := tm.getCluster()
+	if cluster == nil {
+		return nil, errNoBrokersFound
+	}
+
 	addrs := make(map[uint16]string)
-	if partitions, ok := tm.cluster.PartitionIDByTopicName[topic]; ok {
+	if partitions, ok := cluster.PartitionIDByTopicName[topic]; ok {
 		for _, partitionID := range partitions {
-			if addr, ok := tm.cluster.AddrByPartitionID[partitionID]; ok {
+			if addr, ok := cluster.AddrByPartitionID[partitionID]; ok {
 				addrs[partitionID] = addr
 			} else {
 				return nil, errPartitionNotFound
@@ -41,116 +73,202 @@ func (tm *topologyManager) topicPartitionsAddrs(topic string) (*map[uint16]strin
 	return &addrs, nil
 }
 
-func (tm *topologyManager) partitionID(topic string) (uint16, error) {
-	lastPartitionUsedIndex, ok := tm.lastIndexes[topic]
-	if !ok {
-		lastPartitionUsedIndex = 0
+func (tm *topologyManager) partitionID(ctx context.Context, topic string) (uint16, error) {
+	cluster := tm.getCluster()
+	if cluster == nil {
+		return 0, errTopicLeaderNotFound
 	}
-	brokers, ok := tm.cluster.PartitionIDByTopicName[topic]
+
+	brokers, ok := cluster.PartitionIDByTopicName[topic]
 	if !ok {
-		tm.refreshTopology()
+		cluster, _ = tm.refreshTopology(ctx)
+		if cluster == nil {
+			return 0, errTopicLeaderNotFound
+		}
 
-		brokers, ok = tm.cluster.PartitionIDByTopicName[topic]
+		brokers, ok = cluster.PartitionIDByTopicName[topic]
 
 		if !ok {
 			return 0, errTopicLeaderNotFound
 		}
 	}
 
-	var partitionID uint16
-	if tm.lastIndexes[topic] >= uint16(len(brokers)) {
-		tm.lastIndexes[topic] = 0
-	} else {
-		partitionID = brokers[lastPartitionUsedIndex]
-		tm.lastIndexes[topic] = lastPartitionUsedIndex + 1
-	}
+	tm.clusterMu.Lock()
+	lastPartitionUsedIndex := tm.lastIndexes[topic] % uint16(len(brokers))
+	tm.lastIndexes[topic] = lastPartitionUsedIndex + 1
+	tm.clusterMu.Unlock()
 
 	// TODO: zbc-go/issues#40 + zbc-go/issues#48
-	return partitionID, nil
+	return brokers[lastPartitionUsedIndex], nil
 }
 
-func (tm *topologyManager) initTopology() (*zbmsgpack.ClusterTopology, error) {
-	factory := newRequestFactory()
-	responseHandler := newResponseHandler()
+// initTopology will request the topology from the bootstrap brokers in the given order until one of them answers.
+func (tm *topologyManager) initTopology(ctx context.Context) (*zbmsgpack.ClusterTopology, error) {
+	return tm.requestTopology(ctx, tm.bootstrapAddrs)
+}
 
-	resp, err := tm.executeRequest(newRequestWrapper(factory.topologyRequest()))
-	topology := responseHandler.unmarshalTopology(resp)
-	if err != nil {
-		return nil, err
+// refreshTopology will request the topology from the brokers of the cached topology in random order. When none of them
+// is reachable it falls back to the bootstrap brokers.
+func (tm *topologyManager) refreshTopology(ctx context.Context) (*zbmsgpack.ClusterTopology, error) {
+	cluster := tm.getCluster()
+	if cluster == nil {
+		return tm.initTopology(ctx)
 	}
 
-	tm.cluster = &topology
-	return tm.cluster, nil
-}
+	if len(cluster.Brokers) == 0 {
+		return nil, errNoBrokersFound
+	}
 
-func (tm *topologyManager) refreshTopology() (*zbmsgpack.ClusterTopology, error) {
-	rand.Seed(time.Now().Unix())
+	addrs := make([]string, len(cluster.Brokers))
+	for i, index := range rand.Perm(len(cluster.Brokers)) {
+		addrs[i] = cluster.Brokers[index].Addr()
+	}
 
+	topology, err := tm.requestTopology(ctx, addrs)
+	if err == nil || ctx.Err() != nil || tm.isClosed() {
+		return topology, err
+	}
+
+	return tm.initTopology(ctx)
+}
+
+func (tm *topologyManager) requestTopology(ctx context.Context, addrs []string) (*zbmsgpack.ClusterTopology, error) {
 	factory := newRequestFactory()
 	responseHandler := newResponseHandler()
 
-	request := newRequestWrapper(factory.topologyRequest())
-	if len(tm.cluster.Brokers) == 0 {
-		return nil, errNoBrokersFound
-	}
-	broker := tm.cluster.GetRandomBroker()
-	request.addr = broker.Addr() //.Brokers[rand.Int()%len(tm.cluster.Brokers)].Addr() // Get random broker
-	resp, err := tm.executeRequest(request)
-	topology := responseHandler.unmarshalTopology(resp)
-	if err != nil {
-		return nil, err
+	err := errNoBrokersFound
+	for _, addr := range addrs {
+		request := newRequestWrapper(factory.topologyRequest())
+		request.addr = addr
+
+		var resp *Message
+		resp, err = tm.executeRequest(ctx, request)
+		if err != nil {
+			if ctx.Err() != nil || tm.isClosed() {
+				return nil, err
+			}
+			continue
+		}
+
+		topology := responseHandler.unmarshalTopology(resp)
+		tm.setCluster(&topology)
+		return &topology, nil
 	}
 
-	tm.cluster = &topology
-	return tm.cluster, err
+	return nil, err
 }
 
 func (tm *topologyManager) getDestinationAddr(msg *Message) (string, error) {
-	if msg.isTopologyMessage() && tm.cluster == nil {
-		return tm.bootstrapAddr, nil
+	cluster := tm.getCluster()
+	if msg.isTopologyMessage() && cluster == nil {
+		return tm.bootstrapAddrs[0], nil
 	}
 
 	partitionID := msg.forPartitionId()
-	if partitionID == nil {
+	if partitionID == nil || cluster == nil {
 		return "", brokerNotFound
 	}
 
-	if addr, ok := tm.cluster.AddrByPartitionID[*partitionID]; ok {
+	if addr, ok := cluster.AddrByPartitionID[*partitionID]; ok {
 		return addr, nil
 	}
 
 	return "", brokerNotFound
 }
 
-func (tm *topologyManager) executeRequest(request *requestWrapper) (*Message, error) {
-	addr, err := tm.getDestinationAddr(request.payload)
+// executeRequest will send the request and wait for its response. If ctx has no deadline the configured request timeout is applied.
+// When ctx is done before the response arrives, the request is released from the dispatcher and ctx.Err() is returned.
+// Request is sent to request.addr if it is set, otherwise the destination is resolved from the cluster topology.
+// Error response of the broker is returned as *BrokerError.
+func (tm *topologyManager) executeRequest(ctx context.Context, request *requestWrapper) (*Message, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, tm.config.requestTimeout)
+		defer cancel()
+	}
+
+	if tm.isClosed() {
+		return nil, ErrClientClosed
+	}
+
+	if len(request.addr) == 0 {
+		addr, err := tm.getDestinationAddr(request.payload)
+
+		if err == brokerNotFound {
+			return nil, brokerNotFound
+		}
+		request.addr = addr
+	}
+	request.ctx = ctx
+
+	resp, err := tm.roundTrip(ctx, request)
+	if err == nil {
+		return resp, nil
+	}
+
+	if err == ErrClientClosed || ctx.Err() != nil || request.payload.isTopologyMessage() {
+		return nil, err
+	}
+
+	_, isBrokerError := err.(*BrokerError)
+	if tm.getCluster() != nil && (!isBrokerError || IsPartitionNotFound(err)) {
+		tm.refreshTopology(ctx)
+	}
+	return nil, err
+}
+
+// roundTrip will hand the request to the transport and wait for the response. At most requestQueueSize requests are
+// in flight at once, so that they never override each other in the dispatcher.
+func (tm *topologyManager) roundTrip(ctx context.Context, request *requestWrapper) (*Message, error) {
+	select {
+	case tm.inFlight <- struct{}{}:
+	case <-tm.closeCh:
+		return nil, ErrClientClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-tm.inFlight }()
 
-	if err == brokerNotFound {
-		return nil, brokerNotFound
+	select {
+	case tm.topologyWorkload <- request:
+	case <-tm.closeCh:
+		return nil, ErrClientClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
-	request.addr = addr
-	tm.topologyWorkload <- request
 
 	select {
 
 	case resp := <-request.responseCh:
+		if err := resp.brokerError(); err != nil {
+			return nil, err
+		}
 		return resp, nil
 
 	case err := <-request.errorCh:
-		if tm.cluster != nil {
-			tm.refreshTopology()
-		}
 		return nil, err
 
+	case <-tm.closeCh:
+		request.release()
+		return nil, ErrClientClosed
+
+	case <-ctx.Done():
+		request.release()
+		return nil, ctx.Err()
+
 	}
 }
 
 func (tm *topologyManager) topologyTicker() {
 	for {
 		select {
-		case <-time.After(TopologyRefreshInterval * time.Second):
-			if time.Since(tm.cluster.UpdatedAt) > TopologyRefreshInterval*time.Second {
-				tm.topologyWorkload <- newRequestWrapper(newRequestFactory().topologyRequest())
+		case <-tm.closeCh:
+			return
+
+		case <-time.After(tm.config.topologyRefreshInterval):
+			cluster := tm.getCluster()
+			if cluster == nil || time.Since(cluster.UpdatedAt) > tm.config.topologyRefreshInterval {
+				tm.refreshTopology(context.Background())
 			}
 			break
 		}
@@ -160,24 +278,28 @@ func (tm *topologyManager) topologyTicker() {
 func (tm *topologyManager) topologyWorker() {
 	for {
 		select {
+		case <-tm.closeCh:
+			return
+
 		case request := <-tm.topologyWorkload:
 			tm.execTransport(request)
 		}
 	}
 }
 
-func newTopologyManager(bootstrapAddr string) *topologyManager {
+func newTopologyManager(bootstrapAddrs []string, config *clientConfig) *topologyManager {
 	tm := &topologyManager{
-		newTransportManager(),
-		make(chan *requestWrapper, requestQueueSize),
-		make(map[string]uint16),
-		bootstrapAddr,
-		nil,
+		transportManager: newTransportManager(config),
+		topologyWorkload: make(chan *requestWrapper, requestQueueSize),
+		inFlight:         make(chan struct{}, requestQueueSize),
+		lastIndexes:      make(map[string]uint16),
+		bootstrapAddrs:   bootstrapAddrs,
+		cluster:          nil,
+		clusterChangedCh: make(chan struct{}),
 	}
 
 	go tm.topologyWorker()
 	go tm.topologyTicker()
 
-	tm.initTopology()
 	return tm
 }
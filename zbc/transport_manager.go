@@ -2,25 +2,42 @@ package zbc
 
 import (
 	"errors"
+	"sync"
+	"sync/atomic"
 )
 
 var brokerNotFound = errors.New("cannot contact the broker")
 
 type transportManager struct {
+	// Accessed atomically, keep it first for 64-bit alignment on 32-bit platforms.
+	unknownSubscriberEvents uint64
+
+	config *clientConfig
+
 	transportWorkload chan *requestWrapper
 
-	connections map[string]*socket
+	connectionsMu sync.Mutex
+	connections   map[string]*socket
+
+	closeCh   chan struct{}
+	closeOnce sync.Once
 }
 
-// TODO: check if socket in map is unreachable/unhealty than delete that record from the map
 // TODO: keep-alive messages
 
 func (tm *transportManager) getSocket(addr string) (*socket, error) {
-	if conn, ok := tm.connections[addr]; ok {
+	tm.connectionsMu.Lock()
+	defer tm.connectionsMu.Unlock()
+
+	if tm.connections == nil {
+		return nil, ErrClientClosed
+	}
+
+	if conn, ok := tm.connections[addr]; ok && !conn.isClosed() {
 		return conn, nil
 	}
 
-	sock := newSocketStream(addr)
+	sock := newSocketStream(addr, tm.config.socketChunkSize, tm.config.requestTimeout, &tm.unknownSubscriberEvents)
 	if sock == nil {
 		return nil, brokerNotFound
 	}
@@ -29,37 +46,91 @@ func (tm *transportManager) getSocket(addr string) (*socket, error) {
 	return tm.connections[addr], nil
 }
 
+// unknownSubscriberEventsCount returns the number of subscribed events which arrived for an unknown subscriber.
+func (tm *transportManager) unknownSubscriberEventsCount() uint64 {
+	return atomic.LoadUint64(&tm.unknownSubscriberEvents)
+}
+
+func (tm *transportManager) isClosed() bool {
+	select {
+	case <-tm.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// closeTransport will stop the transport worker and tear down all the connections.
+func (tm *transportManager) closeTransport() {
+	tm.closeOnce.Do(func() {
+		close(tm.closeCh)
+
+		tm.connectionsMu.Lock()
+		for _, sock := range tm.connections {
+			sock.teardown()
+		}
+		tm.connections = nil
+		tm.connectionsMu.Unlock()
+	})
+}
 
 func (tm *transportManager) execTransport(request *requestWrapper) {
-	tm.transportWorkload <- request
+	select {
+	case tm.transportWorkload <- request:
+	case <-tm.closeCh:
+	}
 }
 
 func (tm *transportManager) transportWorker() {
 	for {
 		select {
-		case request := <- tm.transportWorkload:
+		case <-tm.closeCh:
+			return
+
+		case request := <-tm.transportWorkload:
+			if request.ctx.Err() != nil {
+				continue
+			}
+
 			sock, err := tm.getSocket(request.addr)
 			if err != nil {
-				request.errorCh <- err
+				request.fail(err)
+				continue
 			}
 
-			request.sock = sock
-			MessageRetry(func() (*Message, error) {
+			request.setSocket(sock)
+			_, err = messageRetry(func() (*Message, error) {
+				if tm.isClosed() {
+					return nil, nil
+				}
 				sock.addTransaction(request)
 				if err := sock.sender(request.payload); err != nil {
+					sock.removeTransaction(request)
 					return nil, err
 				}
 				return nil, nil
-			})
+			}, tm.config.backoffMin, tm.config.backoffMax, tm.config.backoffDeadline)
 
+			if err != nil {
+				request.release()
+				request.fail(err)
+				continue
+			}
+
+			// Caller could give up while we were sending the request.
+			if request.ctx.Err() != nil {
+				request.release()
+			}
 		}
 	}
 }
 
-func newTransportManager() *transportManager {
+func newTransportManager(config *clientConfig) *transportManager {
 	tm := &transportManager{
-		make(chan *requestWrapper, requestQueueSize),
-		make(map[string]*socket),
+		config:            config,
+		transportWorkload: make(chan *requestWrapper, requestQueueSize),
+		connections:       make(map[string]*socket),
+		closeCh:           make(chan struct{}),
 	}
 
 	go tm.transportWorker()
@@ -0,0 +1,43 @@
+package zbmsgpack
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DeployedWorkflow is the workflow event which is written for every workflow of a deployment.
+type DeployedWorkflow struct {
+	State         string `msgpack:"state"`
+	BPMNProcessID string `msgpack:"bpmnProcessId"`
+	Version       int    `msgpack:"version"`
+	BPMNXML       []byte `msgpack:"bpmnXml"`
+	DeploymentKey uint64 `msgpack:"deploymentKey"`
+}
+
+func (w *DeployedWorkflow) String() string {
+	b, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("json marshaling failed\n")
+	}
+	return fmt.Sprintf("%+v", string(b))
+}
+
+// RaftMember is a member of the raft group of a partition.
+type RaftMember struct {
+	Host string `msgpack:"host"`
+	Port uint64 `msgpack:"port"`
+}
+
+// Raft is the event which is written when the members of the raft group of a partition change.
+type Raft struct {
+	State   string       `msgpack:"state"`
+	Members []RaftMember `msgpack:"members"`
+}
+
+func (r *Raft) String() string {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("json marshaling failed\n")
+	}
+	return fmt.Sprintf("%+v", string(b))
+}
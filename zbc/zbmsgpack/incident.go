@@ -0,0 +1,28 @@
+package zbmsgpack
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Incident is raised by the broker when a workflow instance can't proceed, for example when a payload mapping fails.
+type Incident struct {
+	State                string  `msgpack:"state"`
+	ErrorType            string  `msgpack:"errorType,omitempty"`
+	ErrorMessage         string  `msgpack:"errorMessage,omitempty"`
+	FailureEventPosition uint64  `msgpack:"failureEventPosition,omitempty"`
+	BPMNProcessID        string  `msgpack:"bpmnProcessId,omitempty"`
+	WorkflowInstanceKey  uint64  `msgpack:"workflowInstanceKey,omitempty"`
+	ActivityID           string  `msgpack:"activityId,omitempty"`
+	ActivityInstanceKey  uint64  `msgpack:"activityInstanceKey,omitempty"`
+	TaskKey              uint64  `msgpack:"taskKey,omitempty"`
+	Payload              []uint8 `msgpack:"payload,omitempty"`
+}
+
+func (i *Incident) String() string {
+	b, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("json marshaling failed\n")
+	}
+	return fmt.Sprintf("%+v", string(b))
+}
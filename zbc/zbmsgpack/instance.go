@@ -6,11 +6,14 @@ import (
 )
 
 type WorkflowInstance struct {
-	State         string                 `yaml:"state" msgpack:"state"`
-	BPMNProcessID string                 `yaml:"bpmnProcessId" msgpack:"bpmnProcessId"`
-	Version       int                    `yaml:"version" msgpack:"version"`
-	Payload       []uint8                `yaml:"-" msgpack:"payload"`
-	PayloadJSON   map[string]interface{} `yaml:"payload" msgpack:"-"`
+	State               string                 `yaml:"state" msgpack:"state"`
+	BPMNProcessID       string                 `yaml:"bpmnProcessId" msgpack:"bpmnProcessId"`
+	Version             int                    `yaml:"version" msgpack:"version"`
+	WorkflowKey         uint64                 `yaml:"workflowKey" msgpack:"workflowKey,omitempty"`
+	WorkflowInstanceKey uint64                 `yaml:"workflowInstanceKey" msgpack:"workflowInstanceKey,omitempty"`
+	ActivityID          string                 `yaml:"activityId" msgpack:"activityId,omitempty"`
+	Payload             []uint8                `yaml:"-" msgpack:"payload"`
+	PayloadJSON         map[string]interface{} `yaml:"payload" msgpack:"-"`
 }
 
 func (t *WorkflowInstance) String() string {
@@ -0,0 +1,176 @@
+package zbmsgpack
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack"
+)
+
+var errPayloadNotDocument = errors.New("payload must be a document")
+
+// UnmarshalPayload will decode the payload of the task into v. See unmarshalPayload.
+func (t *Task) UnmarshalPayload(v interface{}) error {
+	return unmarshalPayload(t.Payload, v)
+}
+
+// SetPayload will encode v as message pack document and use it as payload of the task.
+func (t *Task) SetPayload(v interface{}) error {
+	b, err := marshalPayload(v)
+	if err != nil {
+		return err
+	}
+	t.Payload = b
+	return nil
+}
+
+// MergePayload will deep merge v into the payload of the task. Nested documents are merged, every other value of v
+// replaces the value in the payload.
+func (t *Task) MergePayload(v interface{}) error {
+	b, err := mergePayload(t.Payload, v)
+	if err != nil {
+		return err
+	}
+	t.Payload = b
+	return nil
+}
+
+// UnmarshalPayload will decode the payload of the workflow instance into v. See unmarshalPayload.
+func (t *WorkflowInstance) UnmarshalPayload(v interface{}) error {
+	return unmarshalPayload(t.Payload, v)
+}
+
+// SetPayload will encode v as message pack document and use it as payload of the workflow instance.
+func (t *WorkflowInstance) SetPayload(v interface{}) error {
+	b, err := marshalPayload(v)
+	if err != nil {
+		return err
+	}
+	t.Payload = b
+	return nil
+}
+
+// SetPayload will encode v as message pack document and use it as payload of the incident.
+func (i *Incident) SetPayload(v interface{}) error {
+	b, err := marshalPayload(v)
+	if err != nil {
+		return err
+	}
+	i.Payload = b
+	return nil
+}
+
+// marshalPayload will encode v with respect to its msgpack struct tags. Payload has to be a document, so v must be a
+// map or a struct.
+func marshalPayload(v interface{}) ([]byte, error) {
+	b, err := msgpack.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if !isDocument(b) {
+		return nil, errPayloadNotDocument
+	}
+	return b, nil
+}
+
+func mergePayload(payload []byte, v interface{}) ([]byte, error) {
+	update, err := marshalPayload(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var updateDocument map[string]interface{}
+	if err := unmarshalPayload(update, &updateDocument); err != nil {
+		return nil, err
+	}
+
+	document := make(map[string]interface{})
+	if err := unmarshalPayload(payload, &document); err != nil {
+		return nil, err
+	}
+
+	return marshalPayload(mergeDocuments(document, updateDocument))
+}
+
+func mergeDocuments(dst, src map[string]interface{}) map[string]interface{} {
+	for key, value := range src {
+		srcDocument, srcOk := value.(map[string]interface{})
+		dstDocument, dstOk := dst[key].(map[string]interface{})
+		if srcOk && dstOk {
+			dst[key] = mergeDocuments(dstDocument, srcDocument)
+			continue
+		}
+		dst[key] = value
+	}
+	return dst
+}
+
+// unmarshalPayload will decode payload into v. Structs are decoded with respect to their msgpack struct tags. For
+// *map[string]interface{} and *interface{} the nested maps are converted to map[string]interface{}, so the result can
+// be passed to encoding/json. An empty payload leaves v untouched.
+func unmarshalPayload(payload []byte, v interface{}) error {
+	if len(payload) == 0 {
+		return nil
+	}
+
+	switch target := v.(type) {
+	case *map[string]interface{}:
+		var raw interface{}
+		if err := msgpack.Unmarshal(payload, &raw); err != nil {
+			return err
+		}
+		document, ok := normalizePayload(raw).(map[string]interface{})
+		if !ok {
+			return errPayloadNotDocument
+		}
+		*target = document
+		return nil
+
+	case *interface{}:
+		var raw interface{}
+		if err := msgpack.Unmarshal(payload, &raw); err != nil {
+			return err
+		}
+		*target = normalizePayload(raw)
+		return nil
+
+	default:
+		return msgpack.Unmarshal(payload, v)
+	}
+}
+
+// normalizePayload will replace every map[interface{}]interface{} with map[string]interface{}.
+func normalizePayload(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		document := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			document[fmt.Sprint(key)] = normalizePayload(item)
+		}
+		return document
+
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = normalizePayload(item)
+		}
+		return value
+
+	case []interface{}:
+		for i, item := range value {
+			value[i] = normalizePayload(item)
+		}
+		return value
+
+	default:
+		return v
+	}
+}
+
+// isDocument reports whether the encoded value is a message pack map.
+func isDocument(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	code := b[0]
+	return (code >= 0x80 && code <= 0x8f) || code == 0xde || code == 0xdf
+}
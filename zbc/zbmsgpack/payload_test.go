@@ -0,0 +1,94 @@
+package zbmsgpack
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type orderPayload struct {
+	OrderID string            `msgpack:"orderId"`
+	Amount  int               `msgpack:"amount"`
+	Items   []string          `msgpack:"items"`
+	Meta    map[string]string `msgpack:"meta"`
+}
+
+func TestTaskPayloadStruct(t *testing.T) {
+	task := &Task{}
+	in := orderPayload{OrderID: "o-1", Amount: 42, Items: []string{"a", "b"}, Meta: map[string]string{"k": "v"}}
+	if err := task.SetPayload(in); err != nil {
+		t.Fatalf("SetPayload failed: %s", err)
+	}
+
+	var out orderPayload
+	if err := task.UnmarshalPayload(&out); err != nil {
+		t.Fatalf("UnmarshalPayload failed: %s", err)
+	}
+	if out.OrderID != in.OrderID || out.Amount != in.Amount || len(out.Items) != 2 || out.Meta["k"] != "v" {
+		t.Fatalf("unexpected payload %+v", out)
+	}
+}
+
+func TestWorkflowInstancePayloadMap(t *testing.T) {
+	instance := &WorkflowInstance{}
+	in := map[string]interface{}{
+		"nested": map[string]interface{}{"list": []interface{}{map[string]interface{}{"x": 1}}},
+	}
+	if err := instance.SetPayload(in); err != nil {
+		t.Fatalf("SetPayload failed: %s", err)
+	}
+
+	var out map[string]interface{}
+	if err := instance.UnmarshalPayload(&out); err != nil {
+		t.Fatalf("UnmarshalPayload failed: %s", err)
+	}
+	if _, err := json.Marshal(out); err != nil {
+		t.Fatalf("payload is not JSON compatible: %s", err)
+	}
+
+	var any interface{}
+	if err := instance.UnmarshalPayload(&any); err != nil {
+		t.Fatalf("UnmarshalPayload failed: %s", err)
+	}
+	if _, err := json.Marshal(any); err != nil {
+		t.Fatalf("payload is not JSON compatible: %s", err)
+	}
+}
+
+func TestTaskMergePayload(t *testing.T) {
+	task := &Task{}
+	task.SetPayload(map[string]interface{}{
+		"orderId":  "o-1",
+		"customer": map[string]interface{}{"name": "jane", "address": map[string]interface{}{"city": "berlin"}},
+	})
+
+	err := task.MergePayload(map[string]interface{}{
+		"paid":     true,
+		"customer": map[string]interface{}{"address": map[string]interface{}{"zip": "10115"}},
+	})
+	if err != nil {
+		t.Fatalf("MergePayload failed: %s", err)
+	}
+
+	var out map[string]interface{}
+	task.UnmarshalPayload(&out)
+	customer := out["customer"].(map[string]interface{})
+	address := customer["address"].(map[string]interface{})
+	if out["orderId"] != "o-1" || out["paid"] != true || customer["name"] != "jane" || address["city"] != "berlin" || address["zip"] != "10115" {
+		t.Fatalf("unexpected merged payload %+v", out)
+	}
+}
+
+func TestSetPayloadRejectsNonDocument(t *testing.T) {
+	task := &Task{}
+	if err := task.SetPayload([]interface{}{1, 2}); err != errPayloadNotDocument {
+		t.Fatalf("expected errPayloadNotDocument, got %v", err)
+	}
+
+	incident := &Incident{}
+	if err := incident.SetPayload("o-1"); err != errPayloadNotDocument {
+		t.Fatalf("expected errPayloadNotDocument, got %v", err)
+	}
+	if err := incident.SetPayload(orderPayload{OrderID: "o-1"}); err != nil || len(incident.Payload) == 0 {
+		t.Fatalf("SetPayload failed: %v", err)
+	}
+}
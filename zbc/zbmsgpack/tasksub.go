@@ -12,6 +12,8 @@ type TaskSubscription struct {
 	LockDuration  uint64 `msgpack:"lockDuration" json:"lockDuration"`
 	LockOwner     string `msgpack:"lockOwner" json:"lockOwner"`
 	Credits       int32  `msgpack:"credits" json:"credits"`
+
+	PartitionID uint16 `msgpack:"-" json:"partitionId"`
 }
 
 func (t *TaskSubscription) String() string {